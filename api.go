@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JSON API served with -api-addr; one server outlives every scan of a watch
+type scanAPI struct {
+	mu       sync.Mutex
+	state    *scanState    // Scan in progress, or the last one to finish
+	running  bool          // A scan is in progress
+	started  time.Time     // Start of the current or last scan
+	duration time.Duration // Duration of the last completed scan
+	scans    int64         // Scans completed
+}
+
+// Progress counts returned by GET /status
+type apiStatus struct {
+	Running         bool    `json:"running"`
+	Scans           int64   `json:"scans_completed"`
+	Hosts           int32   `json:"hosts"`
+	Done            int32   `json:"hosts_done"`
+	Alive           int32   `json:"alive"`
+	Offline         int32   `json:"offline"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"`
+	LastScanSeconds float64 `json:"last_scan_seconds,omitempty"`
+}
+
+// Hosts of the current or last scan returned by GET /results, sorted numerically
+type apiResults struct {
+	Alive   []string `json:"alive"`
+	Offline []string `json:"offline"`
+}
+
+// API server shared by every scan of the process (nil = -api-addr not set)
+var api *scanAPI
+
+// Start serving the API on addr, once per process; the listener is opened up front so a bad address fails the scan
+func startAPI(addr string) error {
+	if api != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	api = &scanAPI{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIResponse(w, api.status())
+	})
+	mux.HandleFunc("GET /results", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIResponse(w, api.results())
+	})
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// Encode an API response as JSON
+func writeAPIResponse(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// Point the API at a new scan
+func (a *scanAPI) begin(state *scanState) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state, a.running, a.started = state, true, time.Now()
+}
+
+// Record the end of a scan; its results stay available until the next one begins
+func (a *scanAPI) finish(duration time.Duration) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running, a.duration = false, duration
+	a.scans++
+}
+
+// Read the progress counters of the current scan
+func (a *scanAPI) status() apiStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	status := apiStatus{Running: a.running, Scans: a.scans, LastScanSeconds: a.duration.Seconds()}
+	if a.state == nil {
+		return status
+	}
+	status.Hosts = atomic.LoadInt32(&a.state.totalHosts)
+	status.Done = atomic.LoadInt32(&a.state.progressCount)
+	status.Alive = atomic.LoadInt32(&a.state.aliveCount)
+	status.Offline = atomic.LoadInt32(&a.state.notAliveCount)
+	if a.running {
+		status.ElapsedSeconds = time.Since(a.started).Seconds()
+	} else {
+		status.ElapsedSeconds = a.state.elapsed.Seconds()
+	}
+	return status
+}
+
+// List the hosts of the current scan found so far, from the state collected for every host
+func (a *scanAPI) results() apiResults {
+	a.mu.Lock()
+	state := a.state
+	a.mu.Unlock()
+	var alive, offline []string
+	if state != nil {
+		state.mu.Lock()
+		for ip, up := range state.hostStates {
+			if up {
+				alive = append(alive, ip)
+			} else {
+				offline = append(offline, ip)
+			}
+		}
+		state.mu.Unlock()
+	}
+	// Empty lists are encoded as [] rather than null
+	return apiResults{Alive: append([]string{}, sortHosts(alive)...), Offline: append([]string{}, sortHosts(offline)...)}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Upper bounds (in milliseconds) of the RTT histogram buckets; the last bucket is open-ended
+var rttBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// Bundles a complete scan record into one zip archive. Host lists are streamed to temporary
+// files during the scan and copied into the archive at the end, so nothing large is buffered.
+type scanArchive struct {
+	path      string
+	started   time.Time
+	mu        sync.Mutex
+	alive     *os.File
+	dead      *os.File
+	aliveW    *bufio.Writer
+	deadW     *bufio.Writer
+	errors    []string
+	histogram []int // Alive hosts per RTT bucket
+}
+
+// Create an archive whose host lists are spooled to temporary files
+func newScanArchive(path string, started time.Time) (*scanArchive, error) {
+	alive, err := os.CreateTemp("", "netping-alive-*")
+	if err != nil {
+		return nil, err
+	}
+	dead, err := os.CreateTemp("", "netping-dead-*")
+	if err != nil {
+		alive.Close()
+		os.Remove(alive.Name())
+		return nil, err
+	}
+	return &scanArchive{
+		path:      path,
+		started:   started,
+		alive:     alive,
+		dead:      dead,
+		aliveW:    bufio.NewWriter(alive),
+		deadW:     bufio.NewWriter(dead),
+		histogram: make([]int, len(rttBuckets)+1),
+	}, nil
+}
+
+// Add a host result to the alive or dead list
+func (a *scanArchive) add(result Result) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	host := result.IP
+	if host == "" {
+		host = result.ResolvedFrom
+	}
+	if !result.Alive {
+		a.deadW.WriteString(host + "\n")
+		return
+	}
+	a.aliveW.WriteString(host + "\n")
+	if result.RTTMs != nil {
+		bucket := len(rttBuckets)
+		for i, bound := range rttBuckets {
+			if *result.RTTMs < bound {
+				bucket = i
+				break
+			}
+		}
+		a.histogram[bucket]++
+	}
+}
+
+// Add a scan error (invalid target line, unresolvable domain, ...)
+func (a *scanArchive) addError(format string, args ...interface{}) {
+	a.mu.Lock()
+	a.errors = append(a.errors, fmt.Sprintf(format, args...))
+	a.mu.Unlock()
+}
+
+// Write every component as a separate entry of the zip archive and remove the temporary files
+func (a *scanArchive) close(summary string, manifest *Manifest) error {
+	defer os.Remove(a.alive.Name())
+	defer os.Remove(a.dead.Name())
+	defer a.alive.Close()
+	defer a.dead.Close()
+
+	if err := a.aliveW.Flush(); err != nil {
+		return err
+	}
+	if err := a.deadW.Flush(); err != nil {
+		return err
+	}
+
+	file, err := os.Create(a.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+
+	// Entries live in a timestamped directory so archives of several runs never clash when extracted
+	dir := "netping-" + a.started.Format("20060102T150405") + "/"
+	entry := func(name string, src io.Reader) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: dir + name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, src)
+		return err
+	}
+
+	for _, spool := range []struct {
+		name string
+		file *os.File
+	}{{"alive-hosts.txt", a.alive}, {"dead-hosts.txt", a.dead}} {
+		if _, err := spool.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := entry(spool.name, spool.file); err != nil {
+			return err
+		}
+	}
+
+	var errorsText bytes.Buffer
+	for _, msg := range a.errors {
+		errorsText.WriteString(msg + "\n")
+	}
+	if err := entry("errors.txt", &errorsText); err != nil {
+		return err
+	}
+
+	var histogram bytes.Buffer
+	for i, count := range a.histogram {
+		if i < len(rttBuckets) {
+			fmt.Fprintf(&histogram, "< %gms\t%d\n", rttBuckets[i], count)
+		} else {
+			fmt.Fprintf(&histogram, ">= %gms\t%d\n", rttBuckets[len(rttBuckets)-1], count)
+		}
+	}
+	if err := entry("rtt-histogram.txt", &histogram); err != nil {
+		return err
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := entry("manifest.json", bytes.NewReader(append(manifestJSON, '\n'))); err != nil {
+		return err
+	}
+	if err := entry("summary.txt", bytes.NewBufferString(summary)); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"pinger/netping"
+)
+
+// Reports the first ARP failure only, since it would repeat for every on-link host
+var arpErrorOnce sync.Once
+
+// Probe a host with ARP if -arp is set and it is on a directly attached subnet; false means ICMP must decide
+func (s *scanState) probeARP(p *netping.Pinger, ip string) (netping.Reply, net.HardwareAddr, bool) {
+	if !s.opts.arp {
+		return netping.Reply{}, nil, false
+	}
+	reply, mac, err := p.ARP(ip)
+	if errors.Is(err, netping.ErrNotOnLink) {
+		return netping.Reply{}, nil, false
+	}
+	if err != nil {
+		arpErrorOnce.Do(func() { errorLog.Printf("Error sending ARP requests, probing with ICMP instead: %v\n", err) })
+		return netping.Reply{}, nil, false
+	}
+	return reply, mac, true
+}
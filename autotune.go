@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pinger/netping"
+)
+
+const (
+	tuneInterval   = 2 * time.Second // How often -auto-intensity measures loss and adjusts the rate
+	tuneMinSamples = 10              // Alive hosts needed in an interval to estimate loss
+	tuneMaxRate    = 100000          // Highest rate -auto-intensity will try
+)
+
+// Raises the packet rate while reply loss stays under a ceiling, then holds it. Loss is estimated
+// from alive hosts whose first probe went unanswered, i.e. that only replied on a retry.
+// In adaptive mode it instead treats the starting rate as a ceiling: it backs off while loss is
+// high and recovers towards the starting rate as replies return.
+type intensityTuner struct {
+	mu        sync.Mutex
+	limiter   *netping.Limiter
+	maxLoss   float64
+	baseRate  int // Starting rate, the ceiling of adaptive mode (0 = -auto-intensity)
+	alive     int // Alive hosts finished in the current interval
+	lost      int // Of those, hosts whose first probe was lost
+	lastGood  int // Highest rate measured within the loss ceiling
+	converged bool
+}
+
+// Create a tuner for the limiter, starting from its configured rate
+func newIntensityTuner(limiter *netping.Limiter, maxLoss float64) *intensityTuner {
+	return &intensityTuner{limiter: limiter, maxLoss: maxLoss}
+}
+
+// Create an adaptive tuner that backs off from the limiter's configured rate when loss climbs
+func newAdaptiveTuner(limiter *netping.Limiter, maxLoss float64) *intensityTuner {
+	return &intensityTuner{limiter: limiter, maxLoss: maxLoss, baseRate: limiter.CurrentRate()}
+}
+
+// Count a finished host towards the current interval's loss estimate
+func (t *intensityTuner) observe(result Result) {
+	if t == nil || !result.Alive {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.alive++
+	if result.Attempt > 1 {
+		t.lost++
+	}
+}
+
+// Adjust the rate every interval until done is closed
+func (t *intensityTuner) run(done <-chan struct{}) {
+	if t == nil {
+		return
+	}
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(tuneInterval):
+		}
+		t.adjust()
+	}
+}
+
+// Measure the loss of the last interval and raise, hold, or lower the rate
+func (t *intensityTuner) adjust() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.alive < tuneMinSamples {
+		return
+	}
+	loss := float64(t.lost) / float64(t.alive)
+	t.alive, t.lost = 0, 0
+	rate := t.limiter.CurrentRate()
+	if t.baseRate > 0 {
+		t.adapt(loss, rate)
+		return
+	}
+
+	switch {
+	case loss <= t.maxLoss && !t.converged:
+		t.lastGood = rate
+		t.limiter.SetRate(min(rate*3/2+1, tuneMaxRate))
+	case loss > t.maxLoss && !t.converged:
+		// Step back to the last rate that stayed within the ceiling and hold it
+		t.converged = true
+		t.limiter.SetRate(max(t.lastGood, rate/2, 1))
+		infoLog.Printf("Auto-intensity: %.1f%% loss at %d probes/s, holding %d probes/s\n", loss*100, rate, t.limiter.CurrentRate())
+	case loss > t.maxLoss:
+		t.limiter.SetRate(max(rate*3/4, 1))
+		infoLog.Printf("Auto-intensity: %.1f%% loss, lowering to %d probes/s\n", loss*100, t.limiter.CurrentRate())
+	}
+}
+
+// Halve the rate while loss is over the ceiling, and win it back a tenth of the starting rate at a time
+func (t *intensityTuner) adapt(loss float64, rate int) {
+	switch {
+	case loss > t.maxLoss:
+		t.limiter.SetRate(max(rate/2, 1))
+		infoLog.Printf("Adaptive rate: %.1f%% loss, backing off to %d probes/s\n", loss*100, t.limiter.CurrentRate())
+	case rate < t.baseRate:
+		t.limiter.SetRate(min(rate+max(t.baseRate/10, 1), t.baseRate))
+	}
+}
+
+// Describe the rate the tuner settled on for the summary
+func (t *intensityTuner) report() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.baseRate > 0 {
+		return fmt.Sprintf("Adaptive rate: %d of %d probes/s at end of scan\n", t.limiter.CurrentRate(), t.baseRate)
+	}
+	state := "still rising at end of scan"
+	if t.converged {
+		state = "converged"
+	}
+	return fmt.Sprintf("Auto-intensity rate: %d probes/s (%s)\n", t.limiter.CurrentRate(), state)
+}
+
+// Parse a loss ceiling such as "2%" or "0.02"
+func parseLoss(value string) (float64, error) {
+	s := strings.TrimSpace(value)
+	percent := strings.HasSuffix(s, "%")
+	loss, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid loss '%s' (expected e.g. 2%% or 0.02)", value)
+	}
+	if percent {
+		loss /= 100
+	}
+	if loss <= 0 || loss >= 1 {
+		return 0, fmt.Errorf("loss '%s' must be between 0 and 100%%", value)
+	}
+	return loss, nil
+}
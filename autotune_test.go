@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseLoss(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"2%", 0.02, false},
+		{" 0.5% ", 0.005, false},
+		{"0.02", 0.02, false},
+		{"50%", 0.5, false},
+		{"0%", 0, true},
+		{"0", 0, true},
+		{"100%", 0, true},
+		{"1", 0, true},
+		{"-2%", 0, true},
+		{"two", 0, true},
+		{"%", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseLoss(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("loss = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
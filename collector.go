@@ -0,0 +1,85 @@
+package main
+
+import "net"
+
+// A host to probe and the target line it came from
+type sweepTarget struct {
+	ip           string
+	resolvedFrom string
+}
+
+// Expand the target lines into the hosts to probe, leaving out excluded and duplicate addresses
+// and reporting invalid lines; domains are kept unresolved, as a target without an IP
+func (s *scanState) expand(lines []string) []sweepTarget {
+	var targets []sweepTarget
+	for _, line := range lines {
+		if isBlock(line) {
+			for ip := range lineHosts(line, s.opts.skipEdges) {
+				if !s.skip(ip) {
+					targets = append(targets, sweepTarget{ip.String(), line})
+				}
+			}
+			s.seen.add(line, s.opts.skipEdges)
+		} else if ip := net.ParseIP(line); ip != nil {
+			if !s.skip(ip) {
+				targets = append(targets, sweepTarget{ip.String(), ""}) // Canonical form, as replies are matched by it
+			}
+			s.seen.add(line, s.opts.skipEdges)
+		} else if ip, _, ok := parseHostPort(line); ok {
+			if !s.isExcluded(net.ParseIP(ip)) {
+				targets = append(targets, sweepTarget{ip, line})
+			}
+		} else if isDomain(line) {
+			targets = append(targets, sweepTarget{"", line})
+		} else {
+			s.handleInvalid(line)
+		}
+	}
+	return targets
+}
+
+// Expand the target lines and resolve their domains up front, in random order with -randomize
+func (s *scanState) expandResolved(lines []string) ([]sweepTarget, []string) {
+	var targets []sweepTarget
+	var ips []string
+	for _, target := range s.expand(lines) {
+		if target.ip != "" {
+			targets = append(targets, target)
+			continue
+		}
+		resolved, ok := s.resolve(target.resolvedFrom)
+		for _, ip := range resolved {
+			targets = append(targets, sweepTarget{ip, target.resolvedFrom})
+		}
+		if !ok {
+			s.handleUnresolved(target.resolvedFrom)
+		}
+	}
+	if s.opts.randomize {
+		shuffleTargets(s.rng, targets)
+	}
+	for _, target := range targets {
+		ips = append(ips, target.ip)
+	}
+	return targets, ips
+}
+
+// Expand the target lines, probe every host from one shared socket, then handle the results
+func (s *scanState) sweep(lines []string) {
+	targets, ips := s.expandResolved(lines)
+
+	replies := s.pinger.Sweep(ips, s.opts.collectWindow, s.opts.sockets)
+	results := make([]Result, len(targets))
+	for i, target := range targets {
+		results[i] = Result{IP: target.ip, ResolvedFrom: target.resolvedFrom}
+		if reply, ok := replies[target.ip]; ok {
+			results[i].Alive, results[i].Attempt, results[i].RTTMs = true, reply.Attempt, rttMs(reply.RTT)
+			results[i].TTL, results[i].OSGuess = reply.TTL, guessOS(reply.TTL)
+			s.confirmResult(&results[i])
+		}
+	}
+	s.followUpAlive(results)
+	for _, result := range results {
+		s.finishResult(result)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "os"
+
+// ANSI colors of verbose host lines
+const (
+	colorGreen  = "\x1b[32m" // Alive hosts
+	colorRed    = "\x1b[31m" // Offline hosts
+	colorYellow = "\x1b[33m" // Unresolved domains
+	colorReset  = "\x1b[0m"
+)
+
+// Check whether a file is a terminal that renders ANSI colors; output redirected to a file or pipe stays plain
+func colorTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	return enableANSI(f)
+}
+
+// Wrap a verbose line in an ANSI color, unless colors are off
+func (s *scanState) colorize(color, line string) string {
+	if !s.colors {
+		return line
+	}
+	return color + line + colorReset
+}
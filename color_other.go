@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// Unix terminals render ANSI escapes as they are
+func enableANSI(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+
+	"pinger/netping"
+)
+
+// Run a single scan
+func runScanCommand(args []string) {
+	fs := newFlagSet("scan")
+	opts := addScanFlags(fs)
+	parseScanFlags(fs, opts, args)
+
+	if summary := runScan(opts); summary.interrupted {
+		os.Exit(exitInterrupted)
+	}
+	if opts.apiAddr != "" {
+		// Keep the results available to API clients until the user is done with them
+		infoLog.Printf("Serving the results on %s until Ctrl-C\n", opts.apiAddr)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+	}
+}
+
+// Parse the command line and environment, then fill in the flags they left out from the -config file
+func parseScanFlags(fs *flag.FlagSet, opts *scanOptions, args []string) {
+	parseFlags(fs, args)
+	if opts.config == "" {
+		return
+	}
+	if err := applyConfig(fs, opts.config); err != nil {
+		log.Fatalf("Error reading config file '%s': %v\n", opts.config, err)
+	}
+}
+
+// Re-run the scan on an interval
+func runWatchCommand(args []string) {
+	fs := newFlagSet("watch")
+	opts := addScanFlags(fs)
+	interval := fs.Duration("interval", 5*time.Minute, "Specify the delay between the start of consecutive scans")
+	count := fs.Int("count", 0, "Specify the number of scans to run (0 = run until interrupted)")
+	webhookURL := fs.String("webhook-url", "", "Specify a URL to POST a JSON message to whenever a host goes up or down between scans")
+	parseScanFlags(fs, opts, args)
+
+	if *interval <= 0 {
+		log.Fatal("Error: -interval must be positive")
+	}
+	hook, err := newWebhook(*webhookURL)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	defer hook.close()
+
+	// Keep stdout for alive hosts only in -live mode, or for the results when they are written to stdout
+	var console io.Writer = os.Stdout
+	if opts.live || opts.outputFile == stdoutPath {
+		console = os.Stderr
+	}
+
+	opts.trackHosts = true
+	var previous map[string]bool
+	for run := 1; *count == 0 || run <= *count; run++ {
+		start := time.Now()
+		fmt.Fprintf(console, "\n[%s] Scan #%d\n", start.Format(time.RFC3339), run)
+		summary := runScan(opts)
+		if previous != nil {
+			reportTransitions(console, previous, summary.hosts)
+			hook.notify(previous, summary.hosts, time.Now())
+		}
+		if summary.interrupted {
+			hook.close()             // os.Exit skips deferred calls; post the last transitions first
+			os.Exit(exitInterrupted) // Ctrl-C stops the watch after writing the partial scan
+		}
+		// Hosts missing from this run keep their last known state
+		if previous == nil {
+			previous = map[string]bool{}
+		}
+		maps.Copy(previous, summary.hosts)
+
+		if *count != 0 && run == *count {
+			break
+		}
+		time.Sleep(time.Until(start.Add(*interval)))
+	}
+}
+
+// Print the hosts that went up or down since the previous scan of a watch
+func reportTransitions(w io.Writer, previous, current map[string]bool) {
+	var up, down []string
+	for ip, alive := range current {
+		if wasAlive, seen := previous[ip]; !seen || wasAlive == alive {
+			continue
+		} else if alive {
+			up = append(up, ip)
+		} else {
+			down = append(down, ip)
+		}
+	}
+	for _, ip := range sortHosts(up) {
+		fmt.Fprintf(w, "Host %s is back online\n", ip)
+	}
+	for _, ip := range sortHosts(down) {
+		fmt.Fprintf(w, "Host %s went offline\n", ip)
+	}
+	fmt.Fprintf(w, "Changes since the previous scan: %d up, %d down\n", len(up), len(down))
+}
+
+// Check every line of the target files without sending any probes
+func runValidateCommand(args []string) {
+	fs := newFlagSet("validate")
+	var targetFiles stringList
+	fs.Var(&targetFiles, "target-file", "Specify a target file to validate; a directory or a pattern such as targets/*.txt validates every matching file, and - reads standard input (repeatable)")
+	skipEdges := fs.Bool("skip-network-broadcast", false, "Enable leaving the network and broadcast addresses of IPv4 CIDR blocks out of the host count")
+	maxHosts := fs.Int64("max-hosts", 1<<20, "Specify the most hosts the targets may expand to, as for scan, which refuses to start past it (0 = no limit)")
+	ipv6HostLimit := fs.Int64("ipv6-host-limit", 256, "Specify the most addresses an IPv6 CIDR range may expand to; larger prefixes are reported, as scan refuses them (256 = a /120)")
+	parseFlags(fs, args)
+
+	if len(targetFiles) == 0 {
+		log.Fatal("Error: -target-file flag is required")
+	}
+	paths, err := expandTargetFiles(targetFiles)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	// Ranges are sized arithmetically rather than enumerated, so a huge prefix is reported instead of counted forever
+	var valid []string
+	var invalidLines int
+	for _, path := range paths {
+		data, err := readTargetFile(path)
+		if err != nil {
+			log.Fatalf("Error opening file '%s': %v\n", path, err)
+		}
+		scanner := newLineScanner(data)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			for _, target := range lineTargets(scanner.Text()) {
+				if err := checkIPv6Range(target, *ipv6HostLimit); err != nil {
+					fmt.Printf("'%s' line %d: %v\n", path, lineNumber, err)
+					invalidLines++
+					continue
+				}
+				if _, ok := lineSize(target, *skipEdges); !ok {
+					fmt.Printf("'%s' line %d: invalid IP, CIDR range, or domain: %s\n", path, lineNumber, target)
+					invalidLines++
+					continue
+				}
+				valid = append(valid, target)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("Error reading file '%s': %v\n", path, err)
+		}
+	}
+
+	totalHosts := expandedSize(valid, *skipEdges)
+	fmt.Printf("Hosts to scan: %d\n", totalHosts)
+	fmt.Printf("Invalid lines: %d\n", invalidLines)
+	tooMany := *maxHosts > 0 && totalHosts > *maxHosts
+	if tooMany {
+		fmt.Printf("The targets expand to more than -max-hosts %d hosts, so scan would refuse to start\n", *maxHosts)
+	}
+	if invalidLines > 0 || tooMany {
+		os.Exit(1)
+	}
+}
+
+// Decode a hex-encoded ICMP message, with or without its IPv4 header
+func runDecodeCommand(args []string) {
+	fs := newFlagSet("decode")
+	hexPtr := fs.String("hex", "", "Specify the packet bytes as a hex string (may also be given as an argument)")
+	parseFlags(fs, args)
+
+	input := *hexPtr
+	if input == "" {
+		input = strings.Join(fs.Args(), "")
+	}
+	input = strings.NewReplacer(" ", "", ":", "", "\n", "").Replace(input)
+	if input == "" {
+		log.Fatal("Error: no packet bytes given")
+	}
+
+	packet, err := hex.DecodeString(input)
+	if err != nil {
+		log.Fatalf("Error decoding hex: %v\n", err)
+	}
+
+	// Strip and print the IPv4 header if the packet starts with one
+	if len(packet) >= ipv4.HeaderLen && packet[0]>>4 == 4 {
+		header, err := ipv4.ParseHeader(packet)
+		if err != nil {
+			log.Fatalf("Error parsing IPv4 header: %v\n", err)
+		}
+		fmt.Printf("IPv4: %s -> %s, TTL %d, length %d\n", header.Src, header.Dst, header.TTL, header.TotalLen)
+		packet = packet[header.Len:]
+	}
+
+	msg, err := icmp.ParseMessage(ipv4.ICMPTypeEchoReply.Protocol(), packet)
+	if err != nil {
+		log.Fatalf("Error parsing ICMP message: %v\n", err)
+	}
+	fmt.Printf("ICMP: type %v, code %d\n", msg.Type, msg.Code)
+	if echo, ok := msg.Body.(*icmp.Echo); ok {
+		fmt.Printf("Echo: id %d, seq %d, payload %q\n", echo.ID, echo.Seq, echo.Data)
+	}
+}
+
+// Send a probe to a known target to check socket privileges and connectivity
+func runSelftestCommand(args []string) {
+	fs := newFlagSet("selftest")
+	target := fs.String("target", "127.0.0.1", "Specify the IP address to probe")
+	parseFlags(fs, args)
+
+	p := netping.NewPinger(netping.NewLimiter(defaultRate, 0), icmpPayload, len(icmpPayload), "")
+	defer p.Close()
+	datagram, err := p.OpenSocket(net.ParseIP(*target))
+	if err != nil {
+		log.Fatalf("FAIL: cannot open an ICMP socket (run as root/Administrator, or allow unprivileged ping with sysctl net.ipv4.ping_group_range): %v\n", err)
+	}
+	if datagram {
+		fmt.Println("OK: unprivileged datagram ICMP socket available (no raw socket privileges; -collect-window, -fragment, and -pmtu will not work)")
+	} else {
+		fmt.Println("OK: raw ICMP socket available")
+	}
+
+	reply, alive := p.IsHostAliveWithRetries(*target)
+	if !alive {
+		log.Fatalf("FAIL: no echo reply from %s\n", *target)
+	}
+	fmt.Printf("OK: echo reply received from %s on attempt %d\n", *target, reply.Attempt)
+}
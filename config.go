@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// One setting of a config file: a flag name and its values (several for repeatable flags)
+type configEntry struct {
+	line   int
+	key    string
+	values []string
+}
+
+// Apply the settings of a -config file to the flags that were not given on the command line
+func applyConfig(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []configEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		entries, err = parseYAMLConfig(data)
+	default:
+		entries, err = parseTOMLConfig(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, entry := range entries {
+		if fs.Lookup(entry.key) == nil || entry.key == "config" {
+			return fmt.Errorf("line %d: unknown setting '%s'", entry.line, entry.key)
+		}
+		if explicit[entry.key] {
+			continue // Command-line flags override the file
+		}
+		for _, value := range entry.values {
+			if err := fs.Set(entry.key, value); err != nil {
+				return fmt.Errorf("line %d: invalid value '%s' for %s: %v", entry.line, value, entry.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Prefix of the environment variables that set flags
+const envPrefix = "NETPING_"
+
+// Name of the environment variable that sets a flag, e.g. NETPING_RETRY_BACKOFF for -retry-backoff
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Apply the NETPING_ environment variables to the flags that were not given on the command line;
+// repeatable flags take a comma-separated list, and empty variables are ignored
+func applyEnv(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value := os.Getenv(envName(f.Name))
+		if value == "" || explicit[f.Name] || err != nil {
+			return
+		}
+		values := []string{value}
+		if _, repeatable := f.Value.(*stringList); repeatable {
+			values = strings.Split(value, ",")
+		}
+		for _, value := range values {
+			if setErr := fs.Set(f.Name, strings.TrimSpace(value)); setErr != nil {
+				err = fmt.Errorf("invalid value '%s' for %s in %s: %v", value, f.Name, envName(f.Name), setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// Parse the TOML subset used for scan profiles: key = value, with strings, numbers, booleans and one-line arrays
+func parseTOMLConfig(data []byte) ([]configEntry, error) {
+	var entries []configEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		values, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		entries = append(entries, configEntry{lineNumber, strings.TrimSpace(key), values})
+	}
+	return entries, scanner.Err()
+}
+
+// Parse the YAML subset used for scan profiles: key: value, with flow [a, b] or block "- item" lists
+func parseYAMLConfig(data []byte) ([]configEntry, error) {
+	var entries []configEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			if len(entries) == 0 {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNumber)
+			}
+			value, err := unquoteConfigValue(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			last := &entries[len(entries)-1]
+			last.values = append(last.values, value)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", lineNumber)
+		}
+		var values []string
+		if value = strings.TrimSpace(value); value != "" {
+			var err error
+			if values, err = parseConfigValue(value); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+		}
+		entries = append(entries, configEntry{lineNumber, strings.TrimSpace(key), values})
+	}
+	return entries, scanner.Err()
+}
+
+// Parse a scalar or a one-line [a, b] array into flag values
+func parseConfigValue(value string) ([]string, error) {
+	if inner, ok := strings.CutPrefix(value, "["); ok {
+		inner, ok = strings.CutSuffix(inner, "]")
+		if !ok {
+			return nil, fmt.Errorf("unterminated array %s", value)
+		}
+		var values []string
+		for _, item := range strings.Split(inner, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			item, err := unquoteConfigValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, item)
+		}
+		return values, nil
+	}
+	scalar, err := unquoteConfigValue(value)
+	return []string{scalar}, err
+}
+
+// Remove the quotes around a string value; bare values are used as written
+func unquoteConfigValue(value string) (string, error) {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1], nil
+	}
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", value)
+		}
+		return unquoted, nil
+	}
+	return value, nil
+}
+
+// Strip a '#' comment that is not inside a quoted string
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++ // Skip the escaped character
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
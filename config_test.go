@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOMLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []configEntry
+		wantErr bool
+	}{
+		{"scalars", "timeout = \"2s\"\nretries = 3\nipv6 = true\n",
+			[]configEntry{{1, "timeout", []string{"2s"}}, {2, "retries", []string{"3"}}, {3, "ipv6", []string{"true"}}}, false},
+		{"comments and blank lines", "# profile\n\nretries = 3 # per host\n",
+			[]configEntry{{3, "retries", []string{"3"}}}, false},
+		{"hash inside a string", "label = \"rack #4\"\n",
+			[]configEntry{{1, "label", []string{"rack #4"}}}, false},
+		{"single quotes", "label = 'a \"b\"'\n",
+			[]configEntry{{1, "label", []string{`a "b"`}}}, false},
+		{"array", "exclude = [\"10.0.0.1\", '10.0.0.2', 10.0.0.3, ]\n",
+			[]configEntry{{1, "exclude", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}}, false},
+		{"empty array", "exclude = []\n", []configEntry{{1, "exclude", nil}}, false},
+		{"missing equals", "retries 3\n", nil, true},
+		{"unterminated array", "exclude = [\"10.0.0.1\"\n", nil, true},
+		{"bad string", "label = \"open\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOMLConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []configEntry
+		wantErr bool
+	}{
+		{"scalars", "---\ntimeout: 2s\nlabel: \"rack #4\" # quoted\n",
+			[]configEntry{{2, "timeout", []string{"2s"}}, {3, "label", []string{"rack #4"}}}, false},
+		{"flow list", "exclude: [10.0.0.1, '10.0.0.2']\n",
+			[]configEntry{{1, "exclude", []string{"10.0.0.1", "10.0.0.2"}}}, false},
+		{"block list", "exclude:\n  - 10.0.0.1\n  - \"10.0.0.2\"\nretries: 2\n",
+			[]configEntry{{1, "exclude", []string{"10.0.0.1", "10.0.0.2"}}, {4, "retries", []string{"2"}}}, false},
+		{"value with colon", "webhook: http://example.com:8080/hook\n",
+			[]configEntry{{1, "webhook", []string{"http://example.com:8080/hook"}}}, false},
+		{"list item without a key", "- 10.0.0.1\n", nil, true},
+		{"missing colon", "retries 2\n", nil, true},
+		{"bad string", "label: \"open\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAMLConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
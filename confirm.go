@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pinger/netping"
+)
+
+// Re-probes a host that looked alive with the host's pinger, so its timeout= override applies, and reports whether it really is
+type confirmer func(p *netping.Pinger, ip string) bool
+
+// Parse a -confirm method: "icmp" for a second echo request, or "tcp:PORT[,PORT...]" for TCP connects
+func parseConfirmMethod(spec string) (confirmer, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case spec == "icmp":
+		return (*netping.Pinger).IsHostAlive, nil
+	case strings.HasPrefix(spec, "tcp:"):
+		ports, err := parsePorts(strings.TrimPrefix(spec, "tcp:"))
+		if err != nil {
+			return nil, err
+		}
+		return func(p *netping.Pinger, ip string) bool { return tcpReachable(ip, ports, p.Timeout) }, nil
+	default:
+		return nil, fmt.Errorf("unknown confirm method '%s' (expected icmp or tcp:PORT[,PORT...])", spec)
+	}
+}
+
+// Check if a host answers a TCP connect on any of the ports; a refused connection also proves it is up
+func tcpReachable(ip string, ports []int, timeout time.Duration) bool {
+	for _, port := range ports {
+		if _, answered := dialTCP(ip, port, timeout); answered {
+			return true
+		}
+	}
+	return false
+}
+
+// Demote an alive result to unconfirmed if the confirmation probe fails
+func (s *scanState) confirmResult(result *Result) {
+	if s.confirm == nil || !result.Alive || s.confirm(s.pingerFor(result.IP, result.ResolvedFrom), result.IP) {
+		return
+	}
+	result.Alive, result.Unconfirmed = false, true
+	atomic.AddInt32(&s.unconfirmedCount, 1)
+}
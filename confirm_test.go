@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"pinger/netping"
+)
+
+func TestConfirmTCPUsesHostTimeout(t *testing.T) {
+	confirm, err := parseConfirmMethod("tcp:22,443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinger := netping.NewPinger(netping.NewLimiter(0, 0), "", 0, "")
+	pinger.Timeout = time.Second
+	s := &scanState{pinger: pinger, confirm: confirm, timeouts: map[string]time.Duration{"10.0.0.6": 5 * time.Second}}
+
+	var timeouts []time.Duration
+	dialTCP = func(ip string, port int, timeout time.Duration) (bool, bool) {
+		timeouts = append(timeouts, timeout)
+		return false, port == 443
+	}
+	defer func() { dialTCP = dialPort }()
+
+	for _, tt := range []struct {
+		ip          string
+		wantTimeout time.Duration
+	}{
+		{"10.0.0.5", time.Second},
+		{"10.0.0.6", 5 * time.Second},
+	} {
+		timeouts = nil
+		result := Result{IP: tt.ip, Alive: true}
+		s.confirmResult(&result)
+		if !result.Alive {
+			t.Errorf("%s: refused connection on 443 did not confirm the host", tt.ip)
+		}
+		for _, timeout := range timeouts {
+			if timeout != tt.wantTimeout {
+				t.Errorf("%s: dial timeout = %v, want %v", tt.ip, timeout, tt.wantTimeout)
+			}
+		}
+		if len(timeouts) != 2 {
+			t.Errorf("%s: dialed %d ports, want 2", tt.ip, len(timeouts))
+		}
+	}
+}
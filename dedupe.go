@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"sort"
+)
+
+// An inclusive range of addresses
+type addrSpan struct {
+	lo, hi netip.Addr
+}
+
+// Addresses covered by the target lines seen so far, used to probe each address only once;
+// ranges are kept as merged spans so large CIDR blocks cost no more than a single line
+type addrSet struct {
+	singles map[netip.Addr]bool
+	spans   []addrSpan // Sorted and non-overlapping
+}
+
+// Create an empty address set
+func newAddrSet() *addrSet {
+	return &addrSet{singles: map[netip.Addr]bool{}}
+}
+
+// Convert a net.IP, keeping IPv4 addresses in their 4-byte form
+func toAddr(ip net.IP) netip.Addr {
+	addr, _ := netip.AddrFromSlice(ip)
+	return addr.Unmap()
+}
+
+// Check whether an address is covered by an earlier line
+func (a *addrSet) contains(ip net.IP) bool {
+	if a == nil {
+		return false
+	}
+	addr := toAddr(ip)
+	if a.singles[addr] {
+		return true
+	}
+	i := sort.Search(len(a.spans), func(i int) bool { return a.spans[i].hi.Compare(addr) >= 0 })
+	return i < len(a.spans) && a.spans[i].lo.Compare(addr) <= 0
+}
+
+// First and last address of a target line; false for domains and invalid lines
+func lineBounds(line string, skipEdges bool) (net.IP, net.IP, bool) {
+	if _, ipNet, err := net.ParseCIDR(line); err == nil {
+		start, end := cidrRange(ipNet, skipEdges)
+		return start, end, true
+	}
+	if start, end, ok := parseIPRange(line); ok {
+		return start, end, true
+	}
+	if ip := net.ParseIP(line); ip != nil {
+		return ip, ip, true
+	}
+	return nil, nil, false
+}
+
+// Add the addresses of a target line; domains and invalid lines are ignored
+func (a *addrSet) add(line string, skipEdges bool) {
+	if a == nil {
+		return
+	}
+	start, end, ok := lineBounds(line, skipEdges)
+	if !ok {
+		return
+	}
+	if start.Equal(end) {
+		a.singles[toAddr(start)] = true
+	} else {
+		a.addSpan(addrSpan{toAddr(start), toAddr(end)})
+	}
+}
+
+// Insert a span, merging it with the spans it overlaps or touches
+func (a *addrSet) addSpan(span addrSpan) {
+	a.spans = mergeSpans(append(a.spans, span))
+}
+
+// Sort spans and merge those that overlap or touch, reusing the slice
+func mergeSpans(spans []addrSpan) []addrSpan {
+	if len(spans) == 0 {
+		return spans
+	}
+	slices.SortFunc(spans, func(x, y addrSpan) int { return x.lo.Compare(y.lo) })
+	merged := spans[:1]
+	for _, next := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if next.lo.Compare(last.hi) <= 0 || (last.hi.Next().IsValid() && next.lo == last.hi.Next()) {
+			if next.hi.Compare(last.hi) > 0 {
+				last.hi = next.hi
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+// Every address of the set as sorted, non-overlapping spans, single addresses included
+func (a *addrSet) allSpans() []addrSpan {
+	if a == nil {
+		return nil
+	}
+	spans := slices.Clone(a.spans)
+	for addr := range a.singles {
+		spans = append(spans, addrSpan{addr, addr})
+	}
+	return mergeSpans(spans)
+}
+
+// Take the addresses of the removed spans out of the kept ones; both must be sorted and non-overlapping
+func subtractSpans(kept, removed []addrSpan) []addrSpan {
+	var result []addrSpan
+	for _, span := range kept {
+		first := sort.Search(len(removed), func(i int) bool { return removed[i].hi.Compare(span.lo) >= 0 })
+		for _, cut := range removed[first:] {
+			if cut.lo.Compare(span.hi) > 0 {
+				break
+			}
+			if cut.lo.Compare(span.lo) > 0 {
+				result = append(result, addrSpan{span.lo, cut.lo.Prev()})
+			}
+			if cut.hi.Compare(span.hi) >= 0 {
+				span.lo = netip.Addr{} // Nothing of the span is left
+				break
+			}
+			span.lo = cut.hi.Next()
+		}
+		if span.lo.IsValid() {
+			result = append(result, span)
+		}
+	}
+	return result
+}
+
+// Count the addresses of a target line left out of the scan: those excluded, and those an earlier line already covered
+func skippedHosts(line string, skipEdges bool, excluded, seen *addrSet) (int32, int32) {
+	var excludedCount, duplicateCount int32
+	if excluded == nil && seen == nil {
+		return 0, 0
+	}
+	if ip, _, ok := parseHostPort(line); ok {
+		// Port checks are never duplicates, not even of a line with the same address
+		if excluded.contains(net.ParseIP(ip)) {
+			return 1, 0
+		}
+		return 0, 0
+	}
+	for ip := range lineHosts(line, skipEdges) {
+		if excluded.contains(ip) {
+			excludedCount++
+		} else if seen.contains(ip) {
+			duplicateCount++
+		}
+	}
+	return excludedCount, duplicateCount
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestAddrSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		skipEdges bool
+		in        []string // Addresses the set must cover
+		out       []string // Addresses it must not
+	}{
+		{"single address", []string{"10.0.0.1"}, false, []string{"10.0.0.1"}, []string{"10.0.0.2"}},
+		{"IPv4-mapped form", []string{"10.0.0.1"}, false, []string{"::ffff:10.0.0.1"}, nil},
+		{"CIDR block", []string{"10.0.0.0/30"}, false, []string{"10.0.0.0", "10.0.0.3"}, []string{"10.0.0.4", "9.255.255.255"}},
+		{"skipped edges", []string{"10.0.0.0/30"}, true, []string{"10.0.0.1", "10.0.0.2"}, []string{"10.0.0.0", "10.0.0.3"}},
+		{"overlapping spans merged", []string{"10.0.0.0/29", "10.0.0.4-10.0.0.12"}, false, []string{"10.0.0.0", "10.0.0.12"}, []string{"10.0.0.13"}},
+		{"adjacent spans merged", []string{"10.0.0.0-3", "10.0.0.4-7"}, false, []string{"10.0.0.3", "10.0.0.4", "10.0.0.7"}, []string{"10.0.0.8"}},
+		{"disjoint spans", []string{"10.0.0.20-30", "10.0.0.0-3"}, false, []string{"10.0.0.2", "10.0.0.25"}, []string{"10.0.0.10"}},
+		{"IPv6", []string{"2001:db8::/126"}, false, []string{"2001:db8::3"}, []string{"2001:db8::4"}},
+		{"domains ignored", []string{"example.com"}, false, nil, []string{"93.184.216.34"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newAddrSet()
+			for _, line := range tt.lines {
+				set.add(line, tt.skipEdges)
+			}
+			for _, ip := range tt.in {
+				if !set.contains(net.ParseIP(ip)) {
+					t.Errorf("%s not in the set", ip)
+				}
+			}
+			for _, ip := range tt.out {
+				if set.contains(net.ParseIP(ip)) {
+					t.Errorf("%s unexpectedly in the set", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestSkippedHosts(t *testing.T) {
+	excluded := newAddrSet()
+	excluded.add("10.0.0.2", false)
+	tests := []struct {
+		line                   string
+		seen                   []string
+		wantExcluded, wantSeen int32
+	}{
+		{"10.0.0.0/30", nil, 1, 0},
+		{"10.0.0.0/30", []string{"10.0.0.0-1"}, 1, 2},
+		{"10.0.0.2:443", nil, 1, 0},
+		{"10.0.0.1:443", []string{"10.0.0.1"}, 0, 0},
+		{"example.com", []string{"10.0.0.0/8"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			seen := newAddrSet()
+			for _, line := range tt.seen {
+				seen.add(line, false)
+			}
+			excludedCount, seenCount := skippedHosts(tt.line, false, excluded, seen)
+			if excludedCount != tt.wantExcluded || seenCount != tt.wantSeen {
+				t.Errorf("skipped = (%d, %d), want (%d, %d)", excludedCount, seenCount, tt.wantExcluded, tt.wantSeen)
+			}
+		})
+	}
+}
+
+func TestFilterResolvedDuplicates(t *testing.T) {
+	listed := newAddrSet()
+	listed.add("10.0.0.1", false)
+	listed.add("10.0.1.0/24", false)
+	s := &scanState{listed: listed, resolvedIPs: map[string]bool{}, totalHosts: 2}
+
+	// Addresses listed directly are left to their own line, and one domain's address is not probed again for another
+	if kept := s.filterResolved([]string{"10.0.0.1", "10.0.0.2", "10.0.1.7"}); !slices.Equal(kept, []string{"10.0.0.2"}) {
+		t.Errorf("first domain kept %v, want [10.0.0.2]", kept)
+	}
+	if kept := s.filterResolved([]string{"10.0.0.2", "10.0.0.3"}); !slices.Equal(kept, []string{"10.0.0.3"}) {
+		t.Errorf("second domain kept %v, want [10.0.0.3]", kept)
+	}
+	if s.duplicates != 3 || s.totalHosts != 2 {
+		t.Errorf("duplicates = %d, total = %d; want 3, 2", s.duplicates, s.totalHosts)
+	}
+}
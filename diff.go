@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+)
+
+const patchContext = 3 // Unchanged lines shown around each change in patch output
+
+// One line of a comparison between two alive sets
+type diffLine struct {
+	op   byte // ' ' unchanged, '-' no longer alive, '+' newly alive
+	host string
+}
+
+// Compare the alive hosts of two previous scans
+func runDiffCommand(args []string) {
+	fs := newFlagSet("diff")
+	format := fs.String("diff-format", "text", "Specify the diff format: text (+/- per changed host) or patch (unified diff)")
+	outputFile := fs.String("output-file", "", "Specify a file to write the diff to (default: standard output)")
+	parseFlags(fs, args)
+
+	if fs.NArg() != 2 {
+		log.Fatal("Error: diff needs two result files: <previous> <current>")
+	}
+	if *format != "text" && *format != "patch" {
+		log.Fatalf("Error: unknown diff format '%s'\n", *format)
+	}
+	previousPath, currentPath := fs.Arg(0), fs.Arg(1)
+
+	previous, err := loadAliveSet(previousPath)
+	if err != nil {
+		log.Fatalf("Error reading file '%s': %v\n", previousPath, err)
+	}
+	current, err := loadAliveSet(currentPath)
+	if err != nil {
+		log.Fatalf("Error reading file '%s': %v\n", currentPath, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputFile != "" {
+		file, err := os.Create(*outputFile)
+		if err != nil {
+			log.Fatalf("Error creating output file '%s': %v\n", *outputFile, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	lines := diffAliveSets(previous, current)
+	if *format == "patch" {
+		writePatch(w, previousPath, currentPath, lines)
+		return
+	}
+	var added, removed int
+	for _, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+		if line.op == '+' {
+			added++
+		} else {
+			removed++
+		}
+		fmt.Fprintf(w, "%c %s\n", line.op, line.host)
+	}
+	fmt.Fprintf(w, "Newly alive: %d\nNo longer alive: %d\n", added, removed)
+}
+
+// Print how the alive hosts changed since a -baseline output; baseline hosts this scan did not probe are left out
+func reportBaseline(w io.Writer, baseline []string, hosts map[string]bool) {
+	var previous, current []string
+	notScanned := 0
+	for _, host := range baseline {
+		if _, scanned := hosts[host]; scanned {
+			previous = append(previous, host)
+		} else {
+			notScanned++
+		}
+	}
+	for host, alive := range hosts {
+		if alive {
+			current = append(current, host)
+		}
+	}
+
+	fmt.Fprintf(w, "\nChanges since the baseline:\n")
+	var added, removed, unchanged int
+	for _, line := range diffAliveSets(previous, current) {
+		switch line.op {
+		case '+':
+			added++
+		case '-':
+			removed++
+		default:
+			unchanged++
+			continue
+		}
+		fmt.Fprintf(w, "%c %s\n", line.op, line.host)
+	}
+	fmt.Fprintf(w, "Newly alive: %d\nNewly offline: %d\n", added, removed)
+	fmt.Fprintf(w, "Unchanged: %d alive, %d offline\n", unchanged, len(hosts)-len(current)-removed)
+	if notScanned > 0 {
+		fmt.Fprintf(w, "Baseline hosts not scanned: %d\n", notScanned)
+	}
+}
+
+// Read the alive hosts of a text output (every line) or a JSON output (alive results only)
+func loadAliveSet(path string) ([]string, error) {
+	format, err := detectInputFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == "json" {
+		return loadTargets(path, format, "alive")
+	}
+	return loadTargets(path, format, "all")
+}
+
+// Sort both alive sets numerically and merge them into a line-by-line comparison
+func diffAliveSets(previous, current []string) []diffLine {
+	previous, current = sortHosts(previous), sortHosts(current)
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(previous) || j < len(current) {
+		switch {
+		case j == len(current) || (i < len(previous) && hostLess(previous[i], current[j])):
+			lines = append(lines, diffLine{'-', previous[i]})
+			i++
+		case i == len(previous) || hostLess(current[j], previous[i]):
+			lines = append(lines, diffLine{'+', current[j]})
+			j++
+		default:
+			lines = append(lines, diffLine{' ', previous[i]})
+			i++
+			j++
+		}
+	}
+	return lines
+}
+
+// Sort hosts numerically and drop duplicates
+func sortHosts(hosts []string) []string {
+	sorted := append([]string(nil), hosts...)
+	sort.Slice(sorted, func(i, j int) bool { return hostLess(sorted[i], sorted[j]) })
+	var unique []string
+	for i, host := range sorted {
+		if i == 0 || host != sorted[i-1] {
+			unique = append(unique, host)
+		}
+	}
+	return unique
+}
+
+// Order IPv4 addresses numerically, then IPv6 addresses numerically, before any domain names, which are ordered alphabetically
+func hostLess(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	switch {
+	case ipA == nil && ipB == nil:
+		return a < b
+	case ipA == nil || ipB == nil:
+		return ipA != nil
+	case (ipA.To4() == nil) != (ipB.To4() == nil):
+		return ipA.To4() != nil
+	}
+	return bytes.Compare(ipA.To16(), ipB.To16()) < 0
+}
+
+// Write the comparison as a unified diff, one hunk per group of nearby changes
+func writePatch(w io.Writer, previousPath, currentPath string, lines []diffLine) {
+	fmt.Fprintf(w, "--- a/%s\n+++ b/%s\n", previousPath, currentPath)
+
+	for start := 0; start < len(lines); {
+		// Find the next change, then extend the hunk while at most twice the context separates changes, as in diff -u
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			return
+		}
+		last := first
+		for next := first + 1; next < len(lines) && next <= last+2*patchContext+1; next++ {
+			if lines[next].op != ' ' {
+				last = next
+			}
+		}
+		from := max(first-patchContext, 0)
+		to := min(last+patchContext+1, len(lines))
+
+		// Line numbers of the hunk in the previous and current files
+		oldStart, newStart := 1, 1
+		for _, line := range lines[:from] {
+			if line.op != '+' {
+				oldStart++
+			}
+			if line.op != '-' {
+				newStart++
+			}
+		}
+		var oldCount, newCount int
+		for _, line := range lines[from:to] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+		// An empty side starts at the line before the hunk, as in diff -u
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range lines[from:to] {
+			fmt.Fprintf(w, "%c%s\n", line.op, line.host)
+		}
+		start = to
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWritePatch(t *testing.T) {
+	// Hosts 10.0.0.from to 10.0.0.to, alive in both scans
+	same := func(from, to int) []diffLine {
+		var lines []diffLine
+		for i := from; i <= to; i++ {
+			lines = append(lines, diffLine{' ', fmt.Sprintf("10.0.0.%d", i)})
+		}
+		return lines
+	}
+	tests := []struct {
+		name  string
+		lines []diffLine
+		want  string
+	}{
+		{"no changes", same(1, 5), ""},
+		{"only additions", []diffLine{{'+', "10.0.0.1"}, {'+', "10.0.0.2"}},
+			"@@ -0,0 +1,2 @@\n+10.0.0.1\n+10.0.0.2\n"},
+		{"only removals", []diffLine{{'-', "10.0.0.1"}},
+			"@@ -1,1 +0,0 @@\n-10.0.0.1\n"},
+		{"context trimmed", append(append(same(1, 5), diffLine{'-', "10.0.0.6"}, diffLine{'+', "10.0.0.7"}), same(8, 12)...),
+			"@@ -3,7 +3,7 @@\n 10.0.0.3\n 10.0.0.4\n 10.0.0.5\n-10.0.0.6\n+10.0.0.7\n 10.0.0.8\n 10.0.0.9\n 10.0.0.10\n"},
+		{"nearby changes share a hunk", append(append([]diffLine{{'+', "10.0.0.1"}}, same(2, 7)...), diffLine{'-', "10.0.0.8"}),
+			"@@ -1,7 +1,7 @@\n+10.0.0.1\n 10.0.0.2\n 10.0.0.3\n 10.0.0.4\n 10.0.0.5\n 10.0.0.6\n 10.0.0.7\n-10.0.0.8\n"},
+		{"distant changes split", append(append([]diffLine{{'+', "10.0.0.1"}}, same(2, 8)...), diffLine{'-', "10.0.0.9"}),
+			"@@ -1,3 +1,4 @@\n+10.0.0.1\n 10.0.0.2\n 10.0.0.3\n 10.0.0.4\n@@ -5,4 +6,3 @@\n 10.0.0.6\n 10.0.0.7\n 10.0.0.8\n-10.0.0.9\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			writePatch(&out, "old.txt", "new.txt", tt.lines)
+			want := "--- a/old.txt\n+++ b/new.txt\n" + tt.want
+			if out.String() != want {
+				t.Errorf("patch =\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Write a Graphviz reachability graph: an edge from the vantage point to every host that replied
+func writeDOT(path, source string, results []Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sorted := append([]Result(nil), results...)
+	sort.Slice(sorted, func(i, j int) bool { return hostLess(dotNode(sorted[i]), dotNode(sorted[j])) })
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "digraph reachability {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintf(w, "  %q [shape=box, style=filled, fillcolor=lightblue];\n", source)
+	for _, result := range sorted {
+		host := dotNode(result)
+		if result.Alive {
+			fmt.Fprintf(w, "  %q [color=darkgreen];\n", host)
+			fmt.Fprintf(w, "  %q -> %q;\n", source, host)
+		} else {
+			fmt.Fprintf(w, "  %q [color=gray, fontcolor=gray];\n", host)
+		}
+	}
+	fmt.Fprintln(w, "}")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Name of a host's node: its address, or the domain if it did not resolve
+func dotNode(result Result) string {
+	if result.IP == "" {
+		return result.ResolvedFrom
+	}
+	return result.IP
+}
+
+// Name the scanning vantage point after the -scanner-id (the host name by default) and, if bound, the source
+// address, so the graphs of several scanners can be merged into one with a source node each
+func vantagePoint(scannerID, source string) string {
+	name := scannerID
+	if name == "" {
+		name = "localhost"
+	}
+	if source != "" {
+		name += " (" + source + ")"
+	}
+	return name
+}
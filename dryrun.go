@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pinger/netping"
+)
+
+// Count the hosts a scan would probe without sending any packets, then exit;
+// exits with status 1 if any target line is invalid
+func dryRun(opts *scanOptions, lines []string, invalidIn map[string]string, excluded *addrSet) {
+	var seen *addrSet
+	if !opts.keepDuplicates {
+		seen = newAddrSet()
+	}
+
+	var totalHosts, excludedCount, duplicates int32
+	var invalidLines int
+	for _, line := range lines {
+		count, ok := countHosts(line, opts.skipEdges)
+		if !ok {
+			fmt.Printf("Invalid IP, CIDR range, or domain in %s: %s\n", invalidIn[line], line)
+			invalidLines++
+			continue
+		}
+		if opts.listHosts {
+			printHosts(line, opts.skipEdges, excluded, seen)
+		}
+		lineExcluded, lineDuplicates := skippedHosts(line, opts.skipEdges, excluded, seen)
+		seen.add(line, opts.skipEdges)
+		totalHosts += count - lineExcluded - lineDuplicates
+		excludedCount += lineExcluded
+		duplicates += lineDuplicates
+	}
+
+	fmt.Printf("Hosts to scan: %d\n", totalHosts)
+	if excludedCount > 0 {
+		fmt.Printf("Excluded addresses skipped: %d\n", excludedCount)
+	}
+	if duplicates > 0 {
+		fmt.Printf("Duplicate addresses skipped: %d\n", duplicates)
+	}
+	fmt.Printf("Invalid lines: %d\n", invalidLines)
+
+	// Alive hosts take one probe and offline hosts take every retry, so the rate bounds the run from both sides
+	if opts.rate > 0 && totalHosts > 0 {
+		fastest := time.Duration(totalHosts) * time.Second / time.Duration(opts.rate)
+		slowest := fastest*time.Duration(opts.retries) + opts.timeout
+		fmt.Printf("Estimated duration at %s: %s (every host alive) to %s (every host offline)\n",
+			netping.FormatRate(opts.rate), fastest.Round(time.Second), slowest.Round(time.Second))
+	}
+
+	if invalidLines > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Print the addresses of a target line that are not excluded and that no earlier line covered; domains are printed unresolved
+func printHosts(line string, skipEdges bool, excluded, seen *addrSet) {
+	if _, _, ok := lineBounds(line, skipEdges); !ok {
+		fmt.Println(line)
+		return
+	}
+	for ip := range lineHosts(line, skipEdges) {
+		if !excluded.contains(ip) && !seen.contains(ip) {
+			fmt.Println(ip)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// Build the set of addresses that must never be probed from -exclude and -exclude-file (nil = nothing excluded)
+func loadExclusions(opts *scanOptions) (*addrSet, error) {
+	entries := append([]string(nil), opts.exclude...)
+	for _, path := range opts.excludeFiles {
+		lines, err := readTargetLines(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read exclude file '%s': %w", path, err)
+		}
+		entries = append(entries, lines...)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	excluded := newAddrSet()
+	for _, entry := range entries {
+		// Edges stay in the set, so a CIDR exclusion covers its whole block
+		if _, _, ok := lineBounds(entry, false); !ok {
+			return nil, fmt.Errorf("invalid exclusion '%s' (expected an IP, CIDR range, or START-END range)", entry)
+		}
+		excluded.add(entry, false)
+	}
+	return excluded, nil
+}
+
+// Check whether a host is excluded, noting it in verbose output
+func (s *scanState) isExcluded(ip net.IP) bool {
+	if !s.excluded.contains(ip) {
+		return false
+	}
+	if s.opts.verbose {
+		fmt.Printf("Host %s is excluded\n", ip)
+	}
+	return true
+}
+
+// Check whether a host must be left out of the scan: excluded, already covered by an earlier target line,
+// or finished by the interrupted run a -resume checkpoint came from
+func (s *scanState) skip(ip net.IP) bool {
+	return s.isExcluded(ip) || s.seen.contains(ip) || s.finished.contains(ip)
+}
+
+// Drop the excluded addresses of a resolved domain, those a -resume checkpoint already has results for, and
+// those an IP or range target line or another domain already covers, and correct the host count, which counted
+// the domain as one host
+func (s *scanState) filterResolved(ips []string) []string {
+	var kept []string
+	for _, ip := range ips {
+		if s.isExcluded(net.ParseIP(ip)) {
+			atomic.AddInt32(&s.excludedCount, 1)
+			continue
+		}
+		if s.finished.contains(net.ParseIP(ip)) {
+			continue // Restored from the checkpoint, and counted with it
+		}
+		if s.resolvedDuplicate(ip) {
+			atomic.AddInt32(&s.duplicates, 1)
+			continue
+		}
+		kept = append(kept, ip)
+	}
+	atomic.AddInt32(&s.totalHosts, int32(len(kept)-1))
+	return kept
+}
+
+// Check whether a resolved address is listed by an IP or range target line, which probes it itself, or was
+// resolved from an earlier domain, claiming it for this domain otherwise
+func (s *scanState) resolvedDuplicate(ip string) bool {
+	if s.listed == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listed.contains(net.ParseIP(ip)) || s.resolvedIPs[ip] {
+		return true
+	}
+	s.resolvedIPs[ip] = true
+	return false
+}
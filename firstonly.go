@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"pinger/netping"
+)
+
+// CIDR block or START-END range probed with -first-only: the first alive host cancels the probes of the rest of the block
+type firstOnlyBlock struct {
+	line   string
+	pinger *netping.Pinger // Copy of the scan's pinger whose probes stop with the block
+	cancel context.CancelFunc
+	once   sync.Once
+	wg     sync.WaitGroup // Probes of the block still running
+}
+
+// Start probing a block; nil without -first-only
+func (s *scanState) newFirstOnlyBlock(line string) *firstOnlyBlock {
+	if !s.opts.firstOnly {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	pinger := *s.pingerFor("", line)
+	pinger.Context = ctx
+	return &firstOnlyBlock{line: line, pinger: &pinger, cancel: cancel}
+}
+
+// Check whether an alive host was already found in the block
+func (b *firstOnlyBlock) found() bool {
+	return b != nil && b.pinger.Context.Err() != nil
+}
+
+// Count a probe of the block as running
+func (b *firstOnlyBlock) begin() {
+	if b != nil {
+		b.wg.Add(1)
+	}
+}
+
+// Release the block's context once its last probe finishes
+func (b *firstOnlyBlock) close() {
+	if b == nil {
+		return
+	}
+	go func() {
+		b.wg.Wait()
+		b.cancel()
+	}()
+}
+
+// Probe every host of a CIDR block or START-END range; with -first-only, the first alive host cancels the rest of the block
+func (s *scanState) scanBlock(line string, wg *sync.WaitGroup) {
+	block := s.newFirstOnlyBlock(line)
+	for ip := range lineHosts(line, s.opts.skipEdges) {
+		if !s.skip(ip) {
+			if block.found() {
+				s.skipFirstOnly()
+			} else if !s.acquire() {
+				break
+			} else {
+				wg.Add(1)
+				block.begin()
+				go func(ip string) {
+					defer wg.Done()
+					defer func() { <-s.sem }() // Release the semaphore slot
+					s.pingBlockHost(block, ip, line)
+				}(ip.String())
+			}
+		}
+	}
+	s.seen.add(line, s.opts.skipEdges)
+	block.close()
+}
+
+// Probe one host of a block, cancelling the rest of the block once it is found alive
+func (s *scanState) pingBlockHost(block *firstOnlyBlock, ip, line string) {
+	if block == nil {
+		s.pingHost(ip, line)
+		return
+	}
+	defer block.wg.Done()
+	result, ok := s.probeHost(block.pinger, ip, line)
+	switch {
+	case ok && result.Alive:
+		block.once.Do(func() {
+			if s.opts.verbose {
+				fmt.Printf("Host %s is alive, skipping the rest of %s\n", ip, line)
+			}
+			block.cancel()
+		})
+	case !ok && !s.interrupted():
+		s.skipFirstOnly()
+	}
+}
+
+// Leave a host out of the scan because its block already has an alive host
+func (s *scanState) skipFirstOnly() {
+	atomic.AddInt32(&s.firstOnlySkipped, 1)
+	atomic.AddInt32(&s.totalHosts, -1)
+}
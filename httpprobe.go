@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"pinger/netping"
+)
+
+// Maximum number of redirects followed by the HTTP probe
+const maxRedirects = 10
+
+// Checks liveness with an HTTP request; any HTTP response counts as alive
+type httpProber struct {
+	client    *http.Client
+	method    string
+	scheme    string
+	userAgent string
+	headers   http.Header
+	retries   int
+}
+
+// Create an HTTP prober whose timeout covers the whole request, redirects included. Only redirects to the
+// target's own address are followed; one to another host ends the probe at the redirect, whose Location is recorded.
+func newHTTPProber(method, scheme, userAgent string, headers []string, timeout time.Duration, retries int) (*httpProber, error) {
+	method = strings.ToUpper(method)
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, fmt.Errorf("unknown HTTP method '%s' (expected GET or HEAD)", method)
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("unknown HTTP scheme '%s' (expected http or https)", scheme)
+	}
+
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid HTTP header '%s' (expected 'Name: value')", header)
+		}
+		parsed.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return &httpProber{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// Liveness only: accept self-signed and mismatched certificates
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects || req.URL.Hostname() != via[0].URL.Hostname() {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		},
+		method:    method,
+		scheme:    scheme,
+		userAgent: userAgent,
+		headers:   parsed,
+		retries:   retries,
+	}, nil
+}
+
+// Send one HTTP request to the host, returning the final status code and the redirect chain
+func (h *httpProber) probe(p *netping.Pinger, ip, hostname string) (int, []string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(p.Context, h.method, h.scheme+"://"+net.JoinHostPort(ip, h.port())+"/", nil)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	for name, values := range h.headers {
+		req.Header[name] = values
+	}
+	if h.userAgent != "" {
+		req.Header.Set("User-Agent", h.userAgent)
+	}
+	if hostname != "" {
+		req.Host = hostname // Reach the right virtual host when the target was a domain
+	}
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	defer resp.Body.Close()
+	rtt := time.Since(start)
+
+	// Walk back from the final request to rebuild the redirect chain
+	var redirects []string
+	for r := resp.Request; r.Response != nil; r = r.Response.Request {
+		redirects = append([]string{r.URL.String()}, redirects...)
+	}
+	// A redirect that was not followed ends the chain with where it pointed
+	if location, err := resp.Location(); err == nil {
+		redirects = append(redirects, location.String())
+	}
+	return resp.StatusCode, redirects, rtt, nil
+}
+
+// Default port of the probe's scheme
+func (h *httpProber) port() string {
+	if h.scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// Probe a host over HTTP with retries, waiting out the pinger's -retry-backoff between attempts,
+// and return the 1-based attempt that got a response
+func (h *httpProber) isHostAliveWithRetries(p *netping.Pinger, ip, hostname string) (int, int, []string, time.Duration, bool) {
+	for i := 0; i < h.retries; i++ {
+		if status, redirects, rtt, err := h.probe(p, ip, hostname); err == nil {
+			return i + 1, status, redirects, rtt, true
+		}
+		if i < h.retries-1 && !p.WaitRetry(i) { // Wait before retrying, but not after the last attempt
+			break
+		}
+	}
+	return 0, 0, nil, 0, false
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"pinger/netping"
+)
+
+func TestHTTPProbeRedirects(t *testing.T) {
+	// The probe always uses the scheme's port
+	listener, err := net.Listen("tcp", "127.0.0.1:80")
+	if err != nil {
+		t.Skipf("cannot listen on port 80: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/home", http.StatusFound)
+		case "/home":
+			http.Redirect(w, r, "http://elsewhere.example/", http.StatusMovedPermanently)
+		}
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	prober, err := newHTTPProber("GET", "http", "", nil, time.Second, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinger := netping.NewPinger(netping.NewLimiter(0, 0), "", 0, "")
+	status, redirects, _, err := prober.probe(pinger, "127.0.0.1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The redirect on the target is followed; the one leaving it is recorded but not requested
+	want := []string{"http://127.0.0.1:80/home", "http://elsewhere.example/"}
+	if status != http.StatusMovedPermanently || !slices.Equal(redirects, want) {
+		t.Errorf("probe = %d %v, want %d %v", status, redirects, http.StatusMovedPermanently, want)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Escape commas, spaces and equals signs in an InfluxDB tag value
+var influxTagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// Format a host result as an InfluxDB line protocol record
+func formatInfluxLine(result Result, source string, at time.Time) string {
+	var b strings.Builder
+	b.WriteString("netping")
+	if result.IP != "" {
+		b.WriteString(",host=" + influxTagEscaper.Replace(result.IP))
+	}
+	// Tag hosts that were scanned by name with the domain they resolved from
+	if isDomain(result.ResolvedFrom) {
+		b.WriteString(",name=" + influxTagEscaper.Replace(result.ResolvedFrom))
+	}
+	if result.PTR != "" {
+		b.WriteString(",ptr=" + influxTagEscaper.Replace(result.PTR)) // Reverse-DNS name from -resolve-ptr
+	}
+	if result.Scanner != "" {
+		b.WriteString(",scanner=" + influxTagEscaper.Replace(result.Scanner))
+	}
+	if source != "" {
+		b.WriteString(",source=" + influxTagEscaper.Replace(source))
+	}
+
+	alive := 0
+	if result.Alive {
+		alive = 1
+	}
+	fmt.Fprintf(&b, " alive=%d", alive)
+	if result.RTTMs != nil {
+		fmt.Fprintf(&b, ",rtt=%g", *result.RTTMs)
+	}
+	if result.Attempt > 0 {
+		fmt.Fprintf(&b, ",attempt=%di", result.Attempt)
+	}
+	if result.LossPct != nil {
+		fmt.Fprintf(&b, ",loss=%g", *result.LossPct)
+	}
+	fmt.Fprintf(&b, " %d", at.UnixNano())
+	return b.String()
+}
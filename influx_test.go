@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatInfluxLineTags(t *testing.T) {
+	rtt := 12.3
+	at := time.Unix(1760000000, 0)
+	tests := []struct {
+		name   string
+		result Result
+		want   string
+	}{
+		{"address", Result{IP: "10.0.0.5", Alive: true, Attempt: 1, RTTMs: &rtt, Scanner: "probe01"},
+			"netping,host=10.0.0.5,scanner=probe01 alive=1,rtt=12.3,attempt=1i 1760000000000000000"},
+		{"domain", Result{IP: "10.0.0.5", ResolvedFrom: "db.example.com"},
+			"netping,host=10.0.0.5,name=db.example.com alive=0 1760000000000000000"},
+		{"ptr", Result{IP: "10.0.0.5", Alive: true, Attempt: 1, PTR: "db 1.example.com"},
+			`netping,host=10.0.0.5,ptr=db\ 1.example.com alive=1,attempt=1i 1760000000000000000`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatInfluxLine(tt.result, "", at); got != tt.want {
+				t.Errorf("line =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"iter"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Check if a line is meant as a START-END range: an IP address followed by a hyphen
+func looksLikeRange(line string) bool {
+	start, _, found := strings.Cut(line, "-")
+	return found && net.ParseIP(start) != nil
+}
+
+// Parse an IPv4 range written as START-END (192.168.1.10-192.168.1.50) or with the last
+// octet of the end only (192.168.1.10-50); the end must not come before the start
+func parseIPRange(line string) (net.IP, net.IP, bool) {
+	from, to, found := strings.Cut(line, "-")
+	if !found {
+		return nil, nil, false
+	}
+	start := net.ParseIP(strings.TrimSpace(from)).To4()
+	if start == nil {
+		return nil, nil, false
+	}
+	to = strings.TrimSpace(to)
+	end := net.ParseIP(to).To4()
+	if end == nil {
+		octet, err := strconv.Atoi(to)
+		if err != nil || octet < 0 || octet > 255 {
+			return nil, nil, false
+		}
+		end = net.IPv4(start[0], start[1], start[2], byte(octet)).To4()
+	}
+	if bytes.Compare(start, end) > 0 {
+		return nil, nil, false
+	}
+	return start, end, true
+}
+
+// First and last address of a CIDR block; with skipEdges, IPv4 blocks larger than a /31
+// leave out their network and broadcast addresses
+func cidrRange(ipNet *net.IPNet, skipEdges bool) (net.IP, net.IP) {
+	start := ipNet.IP.Mask(ipNet.Mask)
+	end := make(net.IP, len(start))
+	for i := range start {
+		end[i] = start[i] | ^ipNet.Mask[i]
+	}
+	if ones, bits := ipNet.Mask.Size(); skipEdges && bits == 32 && ones < 31 {
+		incrementIP(start)
+		end[3]--
+	}
+	return start, end
+}
+
+// Check that an IPv6 CIDR range expands to at most limit addresses; a /64 would otherwise be enumerated forever
+func checkIPv6Range(line string, limit int64) error {
+	_, ipNet, err := net.ParseCIDR(line)
+	if err != nil || ipNet.IP.To4() != nil {
+		return nil
+	}
+	ones, bits := ipNet.Mask.Size()
+	if hostBits := bits - ones; hostBits >= 63 || int64(1)<<hostBits > limit {
+		return fmt.Errorf("IPv6 range %s has more than %d addresses (use a longer prefix or raise -ipv6-host-limit)", line, limit)
+	}
+	return nil
+}
+
+// Check whether a target line is a CIDR range or a START-END range
+func isBlock(line string) bool {
+	if _, _, err := net.ParseCIDR(line); err == nil {
+		return true
+	}
+	_, _, ok := parseIPRange(line)
+	return ok
+}
+
+// Every address of a target line, in order; nothing for domains and invalid lines. The counting
+// pass and every scan mode expand lines through this, so the host count matches the probes sent
+func lineHosts(line string, skipEdges bool) iter.Seq[net.IP] {
+	return func(yield func(net.IP) bool) {
+		start, end, ok := lineBounds(line, skipEdges)
+		if !ok || bytes.Compare(start, end) > 0 {
+			return
+		}
+		// Each address is yielded as its own copy, so callers may keep it while the cursor moves on
+		ip := slices.Clone(start)
+		for yield(slices.Clone(ip)) && !ip.Equal(end) {
+			incrementIP(ip)
+		}
+	}
+}
+
+// Number of addresses in a range, both ends included
+func rangeSize(start, end net.IP) int64 {
+	return int64(ipv4ToUint(end)) - int64(ipv4ToUint(start)) + 1
+}
+
+// Convert an IPv4 address to an integer
+func ipv4ToUint(ip net.IP) uint32 {
+	ip = ip.To4()
+	return uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+}
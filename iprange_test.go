@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestParseIPRange(t *testing.T) {
+	tests := []struct {
+		line       string
+		start, end string // Empty when the line is not a range
+	}{
+		{"192.168.1.10-192.168.1.50", "192.168.1.10", "192.168.1.50"},
+		{"192.168.1.10-50", "192.168.1.10", "192.168.1.50"},
+		{"192.168.1.10 - 50", "192.168.1.10", "192.168.1.50"},
+		{"10.0.0.5-10.0.0.5", "10.0.0.5", "10.0.0.5"},
+		{"10.0.0.255-10.0.1.0", "10.0.0.255", "10.0.1.0"},
+		{"192.168.1.50-10", "", ""},
+		{"192.168.1.10-256", "", ""},
+		{"192.168.1.10--1", "", ""},
+		{"192.168.1.10", "", ""},
+		{"example.com-50", "", ""},
+		{"2001:db8::1-2001:db8::5", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			start, end, ok := parseIPRange(tt.line)
+			if ok != (tt.start != "") {
+				t.Fatalf("ok = %v, want %v", ok, tt.start != "")
+			}
+			if ok && (start.String() != tt.start || end.String() != tt.end) {
+				t.Errorf("range = %s-%s, want %s-%s", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestLineHosts(t *testing.T) {
+	tests := []struct {
+		line      string
+		skipEdges bool
+		want      []string
+	}{
+		{"10.0.0.1", false, []string{"10.0.0.1"}},
+		{"10.0.0.0/30", false, []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"}},
+		{"10.0.0.0/30", true, []string{"10.0.0.1", "10.0.0.2"}},
+		{"10.0.0.0/31", true, []string{"10.0.0.0", "10.0.0.1"}},
+		{"10.0.0.7/32", true, []string{"10.0.0.7"}},
+		{"10.0.0.254-10.0.1.1", false, []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{"10.0.0.8-9", true, []string{"10.0.0.8", "10.0.0.9"}},
+		{"2001:db8::/127", true, []string{"2001:db8::", "2001:db8::1"}},
+		{"example.com", false, nil},
+		{"not a target", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			var got []string
+			for ip := range lineHosts(tt.line, tt.skipEdges) {
+				got = append(got, ip.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("hosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineHostsYieldsCopies(t *testing.T) {
+	var kept []net.IP
+	for ip := range lineHosts("10.0.0.0/30", false) {
+		kept = append(kept, ip)
+	}
+	if kept[0].String() != "10.0.0.0" || kept[3].String() != "10.0.0.3" {
+		t.Errorf("kept addresses changed as the range advanced: %v", kept)
+	}
+}
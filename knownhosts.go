@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Persistent set of every host seen alive across runs
+type knownHosts struct {
+	path  string
+	mu    sync.Mutex
+	hosts map[string]bool
+	added int // Hosts added during this run
+}
+
+// Load the known-hosts file, starting empty if it does not exist yet
+func loadKnownHosts(path string) (*knownHosts, error) {
+	k := &knownHosts{path: path, hosts: map[string]bool{}}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if host := strings.TrimSpace(scanner.Text()); host != "" {
+			k.hosts[host] = true
+		}
+	}
+	return k, scanner.Err()
+}
+
+// Add a host to the known set, reporting whether it had never been seen before
+func (k *knownHosts) addIfNew(host string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.hosts[host] {
+		return false
+	}
+	k.hosts[host] = true
+	k.added++
+	return true
+}
+
+// Write the known set to a temporary file and rename it into place, so a crash never leaves it half-written
+func (k *knownHosts) save() error {
+	k.mu.Lock()
+	hosts := make([]string, 0, len(k.hosts))
+	for host := range k.hosts {
+		hosts = append(hosts, host)
+	}
+	k.mu.Unlock()
+	sort.Strings(hosts)
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once the rename succeeds
+
+	writer := bufio.NewWriter(tmp)
+	for _, host := range hosts {
+		writer.WriteString(host + "\n")
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
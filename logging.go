@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"slices"
+)
+
+// Log levels accepted by -log-level, from the fewest messages to the most
+var logLevels = []string{"error", "warn", "info", "debug"}
+
+// One logger per level; the levels below -log-level write to io.Discard
+var (
+	errorLog = log.Default()
+	warnLog  = log.Default()
+	infoLog  = log.Default()
+	debugLog = log.New(io.Discard, "", 0)
+)
+
+// Enable the loggers up to and including the named level
+func setLogLevel(name string) error {
+	level := slices.Index(logLevels, name)
+	if level < 0 {
+		return fmt.Errorf("unknown log level '%s' (expected error, warn, info, or debug)", name)
+	}
+	loggers := []**log.Logger{&errorLog, &warnLog, &infoLog, &debugLog}
+	for i, logger := range loggers {
+		if i <= level {
+			*logger = log.Default()
+		} else {
+			*logger = log.New(io.Discard, "", 0)
+		}
+	}
+	return nil
+}
+
+// Check the command line for -quiet before the flags are parsed, so the logo can be left out too
+func quietRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-quiet", "--quiet", "-quiet=true", "--quiet=true":
+			return true
+		}
+	}
+	return false
+}
+
+// Console output of a scan: progress and notes are dropped with -quiet, but not the summary
+func noteWriter(console io.Writer, quiet bool) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return console
+}
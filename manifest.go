@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+)
+
+// Tool version, overridable at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// Record of a scan's exact configuration and outcome, for reproducibility and auditing
+type Manifest struct {
+	Tool       string            `json:"tool"`
+	Version    string            `json:"version"`
+	Command    string            `json:"command"`
+	ScannerID  string            `json:"scanner_id"`
+	Seed       uint64            `json:"seed"`  // Seed of the -sample draw and -randomize order; passing it as -seed repeats them
+	Flags      map[string]string `json:"flags"` // Effective value of every flag, defaults included
+	Targets    []TargetSource    `json:"targets"`
+	TotalHosts int32             `json:"total_hosts"`
+	Alive      int32             `json:"alive"`
+	Offline    int32             `json:"offline"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+}
+
+// A target file and the checksum of its contents at scan time
+type TargetSource struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// Flags whose values usually carry credentials, such as a token in a webhook URL or an Authorization header;
+// the manifest only records that they were set
+var redactedFlags = map[string]bool{"webhook-url": true, "http-header": true}
+
+// Value recorded in place of a redacted flag that was set
+const redactedValue = "(redacted)"
+
+// Create a manifest from the parsed flags of a command
+func newManifest(fs *flag.FlagSet, scannerID string, seed uint64, start time.Time) *Manifest {
+	m := &Manifest{
+		Tool:      "NetPing",
+		Version:   version,
+		Command:   fs.Name(),
+		ScannerID: scannerID,
+		Seed:      seed,
+		Flags:     map[string]string{},
+		StartTime: start.UTC(),
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if redactedFlags[f.Name] && value != "" {
+			value = redactedValue
+		}
+		m.Flags[f.Name] = value
+	})
+	return m
+}
+
+// Default scanner identity: the machine's hostname
+func defaultScannerID() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// Add a target file and its SHA-256 checksum to the manifest
+func (m *Manifest) addTargetSource(path string) error {
+	data, err := readTargetFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	m.Targets = append(m.Targets, TargetSource{Path: path, SHA256: hex.EncodeToString(sum[:])})
+	return nil
+}
+
+// Write the manifest as indented JSON
+func (m *Manifest) write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Prometheus metrics served with -metrics-addr; one server outlives every scan of a watch
+type scanMetrics struct {
+	mu           sync.Mutex
+	state        *scanState    // Scan in progress, or the last one to finish
+	scans        int64         // Scans completed
+	alive        int64         // Alive results across every scan
+	offline      int64         // Offline results across every scan
+	lastDuration time.Duration // Duration of the last completed scan
+	buckets      []int64       // RTT histogram across every scan, one count per rttBuckets bound
+	rttSum       float64       // Sum of the RTTs in the histogram, in seconds
+	rttCount     int64
+}
+
+// Metrics server shared by every scan of the process (nil = -metrics-addr not set)
+var metrics *scanMetrics
+
+// Start serving metrics on addr, once per process; the listener is opened up front so a bad address fails the scan
+func startMetrics(addr string) error {
+	if metrics != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	metrics = &scanMetrics{buckets: make([]int64, len(rttBuckets))}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.write(w)
+	})
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// Point the gauges at a new scan; the histogram keeps accumulating, as Prometheus expects of its buckets
+func (m *scanMetrics) begin(state *scanState) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+}
+
+// Count a host result and add its RTT to the histogram
+func (m *scanMetrics) observe(result Result) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !result.Alive {
+		m.offline++
+		return
+	}
+	m.alive++
+	if result.RTTMs == nil {
+		return
+	}
+	for i, bound := range rttBuckets {
+		if *result.RTTMs <= bound {
+			m.buckets[i]++
+		}
+	}
+	m.rttSum += *result.RTTMs / 1000
+	m.rttCount++
+}
+
+// Record the end of a scan
+func (m *scanMetrics) finish(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans++
+	m.lastDuration = duration
+}
+
+// Write the metrics in the Prometheus text exposition format
+func (m *scanMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total, progress, alive, offline int32
+	if m.state != nil {
+		total = atomic.LoadInt32(&m.state.totalHosts)
+		progress = atomic.LoadInt32(&m.state.progressCount)
+		alive = atomic.LoadInt32(&m.state.aliveCount)
+		offline = atomic.LoadInt32(&m.state.notAliveCount)
+	}
+	gauge := func(name, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, help, name, name, value)
+	}
+	gauge("netping_scan_hosts", "Hosts to probe in the current scan.", total)
+	gauge("netping_scan_hosts_done", "Hosts probed so far in the current scan.", progress)
+	gauge("netping_scan_alive_hosts", "Alive hosts found so far in the current scan.", alive)
+	gauge("netping_scan_offline_hosts", "Offline hosts found so far in the current scan.", offline)
+	gauge("netping_last_scan_duration_seconds", "Duration of the last completed scan.", m.lastDuration.Seconds())
+
+	fmt.Fprintf(w, "# HELP netping_scans_total Scans completed.\n# TYPE netping_scans_total counter\nnetping_scans_total %d\n", m.scans)
+	fmt.Fprintf(w, "# HELP netping_hosts_total Host results across every scan, by state.\n# TYPE netping_hosts_total counter\n")
+	fmt.Fprintf(w, "netping_hosts_total{state=\"alive\"} %d\nnetping_hosts_total{state=\"offline\"} %d\n", m.alive, m.offline)
+
+	// Buckets are counted individually as RTTs arrive, so they are already cumulative
+	fmt.Fprintf(w, "# HELP netping_rtt_seconds Round-trip times of the alive hosts of every scan.\n# TYPE netping_rtt_seconds histogram\n")
+	for i, bound := range rttBuckets {
+		fmt.Fprintf(w, "netping_rtt_seconds_bucket{le=\"%g\"} %d\n", bound/1000, m.buckets[i])
+	}
+	fmt.Fprintf(w, "netping_rtt_seconds_bucket{le=\"+Inf\"} %d\n", m.rttCount)
+	fmt.Fprintf(w, "netping_rtt_seconds_sum %g\nnetping_rtt_seconds_count %d\n", m.rttSum, m.rttCount)
+}
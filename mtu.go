@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// Find the interface probes will leave through: the named one, or the one routing to the first IP target
+func outgoingInterface(name string, targets []string) (*net.Interface, error) {
+	if name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown interface '%s': %v", name, err)
+		}
+		return iface, nil
+	}
+
+	// Dialing UDP sends nothing but lets the kernel pick the route and local address
+	for _, target := range targets {
+		ip := net.ParseIP(target)
+		if ip == nil {
+			if ipAddr, ipNet, err := net.ParseCIDR(target); err == nil && ipNet != nil {
+				ip = ipAddr
+			} else if start, _, ok := parseIPRange(target); ok {
+				ip = start
+			}
+		}
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+		conn, err := net.Dial("udp4", net.JoinHostPort(ip.String(), "9"))
+		if err != nil {
+			return nil, nil
+		}
+		local := conn.LocalAddr().(*net.UDPAddr).IP
+		conn.Close()
+		return interfaceWithIP(local), nil
+	}
+	return nil, nil
+}
+
+// Find the interface that owns the -source address; probes cannot leave from an address this host does not have
+func sourceInterface(addr string) (*net.Interface, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source address '%s'", addr)
+	}
+	iface := interfaceWithIP(ip)
+	if iface == nil {
+		return nil, fmt.Errorf("source address %s is not assigned to any interface of this host", addr)
+	}
+	return iface, nil
+}
+
+// Find the interface that owns a local IP address
+func interfaceWithIP(ip net.IP) *net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &ifaces[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Return the first IPv4 address of an interface, used to bind the ICMP socket
+func interfaceIPv4(iface *net.Interface) (string, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("interface '%s' has no IPv4 address", iface.Name)
+}
@@ -0,0 +1,45 @@
+package main
+
+import "sync"
+
+// Expand the target lines and probe every host from the pinger's single sender loop, handling each result as its host finishes.
+// This is not the default: it expands every target before the first send, ignores timeout= annotations, and leaves out
+// the probes that run per host, so the worker path in scan.go stays until Pinger.Scan can take those on.
+func (s *scanState) scanMux(lines []string) {
+	targets, ips := s.expandResolved(lines)
+	resolvedFrom := map[string][]string{} // Target lines of each address, in probe order
+	for _, target := range targets {
+		resolvedFrom[target.ip] = append(resolvedFrom[target.ip], target.resolvedFrom)
+	}
+
+	var wg sync.WaitGroup
+	for reply := range s.pinger.Scan(ips) {
+		result := Result{IP: reply.Target, ResolvedFrom: resolvedFrom[reply.Target][0]}
+		resolvedFrom[reply.Target] = resolvedFrom[reply.Target][1:]
+		if reply.Err != nil {
+			errorLog.Printf("Error probing %s: %v\n", reply.Target, reply.Err)
+		}
+		if reply.Alive {
+			result.Alive, result.Attempt, result.RTTMs = true, reply.Attempt, rttMs(reply.RTT)
+			result.TTL, result.OSGuess = reply.TTL, guessOS(reply.TTL)
+		}
+		// Confirmation probes, PTR lookups and port checks run beside the scan, so the result loop keeps up with the replies
+		if result.Alive && (s.confirm != nil || s.opts.resolvePTR || s.portScan != nil) {
+			if !s.acquire() {
+				break
+			}
+			wg.Add(1)
+			go func(result Result) {
+				defer wg.Done()
+				defer func() { <-s.sem }() // Release the semaphore slot
+				s.confirmResult(&result)
+				s.lookupPTR(&result)
+				s.scanPorts(&result)
+				s.finishResult(result)
+			}(result)
+			continue
+		}
+		s.finishResult(result)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package netping
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+var (
+	ErrNotOnLink      = errors.New("target is not on a directly attached IPv4 subnet")
+	ErrARPUnsupported = errors.New("ARP probes are not supported on this platform")
+)
+
+// ARP operation codes
+const (
+	arpRequest = 1
+	arpReply   = 2
+)
+
+// Size of an Ethernet/IPv4 ARP message and of the Ethernet header in front of it, for pacing
+const (
+	arpPacketLen      = 28
+	ethernetHeaderLen = 14
+)
+
+// Look up the MAC address of a host on a directly attached IPv4 subnet with ARP requests, using the
+// pinger's timeout, retries and backoff. A nil address means no reply; ErrNotOnLink means the target
+// must be probed with ICMP instead. Needs root/CAP_NET_RAW on Linux.
+func (p *Pinger) ARP(target string) (Reply, net.HardwareAddr, error) {
+	ip := net.ParseIP(target).To4()
+	if ip == nil {
+		return Reply{}, nil, ErrNotOnLink
+	}
+	iface, source, ok := onLinkInterface(ip)
+	if !ok {
+		return Reply{}, nil, ErrNotOnLink
+	}
+	for i := 0; i < p.Retries && p.Context.Err() == nil; i++ {
+		sent := time.Now()
+		mac, err := p.arpRequest(iface, source, ip)
+		if err != nil {
+			return Reply{}, nil, err
+		}
+		if mac != nil {
+			return Reply{Attempt: i + 1, RTT: time.Since(sent)}, mac, nil
+		}
+		if i < p.Retries-1 && !p.sleep(p.retryDelay(i)) { // Wait before retrying, but not after the last attempt
+			break
+		}
+	}
+	return Reply{}, nil, nil
+}
+
+// Find the Ethernet interface whose IPv4 subnet holds the target, with our address on it; the
+// host's own addresses are left to ICMP, since nothing answers an ARP request for them
+func onLinkInterface(ip net.IP) (*net.Interface, net.IP, bool) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, false
+	}
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || !ipNet.Contains(ip) {
+				continue
+			}
+			if ipNet.IP.Equal(ip) {
+				return nil, nil, false
+			}
+			return iface, ipNet.IP.To4(), true
+		}
+	}
+	return nil, nil, false
+}
+
+// Build an Ethernet/IPv4 ARP message; the target hardware address is left zero
+func arpPacket(op uint16, senderMAC net.HardwareAddr, senderIP, targetIP net.IP) []byte {
+	packet := make([]byte, arpPacketLen)
+	binary.BigEndian.PutUint16(packet[0:], 1)      // Hardware type: Ethernet
+	binary.BigEndian.PutUint16(packet[2:], 0x0800) // Protocol type: IPv4
+	packet[4], packet[5] = 6, 4
+	binary.BigEndian.PutUint16(packet[6:], op)
+	copy(packet[8:14], senderMAC)
+	copy(packet[14:18], senderIP.To4())
+	copy(packet[24:28], targetIP.To4())
+	return packet
+}
+
+// Return the sender's MAC address if the packet is an ARP reply from the target
+func parseARPReply(packet []byte, target net.IP) (net.HardwareAddr, bool) {
+	if len(packet) < arpPacketLen || binary.BigEndian.Uint16(packet[6:]) != arpReply || !bytes.Equal(packet[14:18], target.To4()) {
+		return nil, false
+	}
+	return net.HardwareAddr(bytes.Clone(packet[8:14])), true
+}
@@ -0,0 +1,75 @@
+package netping
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Check that ARP requests can be sent: opening a packet socket needs root/CAP_NET_RAW
+func CheckARP() error {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return err
+	}
+	return unix.Close(fd)
+}
+
+// Send one ARP request for the target from the interface and wait for the matching reply (nil = none)
+func (p *Pinger) arpRequest(iface *net.Interface, source, target net.IP) (net.HardwareAddr, error) {
+	// Datagram packet sockets let the kernel build the Ethernet header from the destination address
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index}); err != nil {
+		return nil, err
+	}
+
+	broadcast := &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ARP), Ifindex: iface.Index, Halen: 6}
+	copy(broadcast.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	if !p.pace(ethernetHeaderLen + arpPacketLen) {
+		return nil, nil // Cancelled before the request went out
+	}
+	err = unix.Sendto(fd, arpPacket(arpRequest, iface.HardwareAddr, source, target), 0, broadcast)
+	p.Breaker.record(err != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait in short slices so a cancelled context is noticed before the timeout
+	deadline := time.Now().Add(p.Timeout)
+	buffer := make([]byte, 128)
+	for p.Context.Err() == nil {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		timeout := unix.NsecToTimeval(min(left, 100*time.Millisecond).Nanoseconds())
+		if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+			return nil, err
+		}
+		n, _, err := unix.Recvfrom(fd, buffer, 0)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mac, ok := parseARPReply(buffer[:n], target); ok {
+			return mac, nil
+		}
+	}
+	return nil, nil
+}
+
+// Convert a 16-bit value to network byte order, as packet sockets expect their protocol number
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
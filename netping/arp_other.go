@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package netping
+
+import "net"
+
+// Check that ARP requests can be sent; only Linux and Windows are supported
+func CheckARP() error {
+	return ErrARPUnsupported
+}
+
+// ARP requests need a platform-specific raw packet interface
+func (p *Pinger) arpRequest(iface *net.Interface, source, target net.IP) (net.HardwareAddr, error) {
+	return nil, ErrARPUnsupported
+}
@@ -0,0 +1,41 @@
+package netping
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSendARP = windows.NewLazySystemDLL("iphlpapi.dll").NewProc("SendARP")
+
+// Check that ARP requests can be sent: SendARP needs no special privileges
+func CheckARP() error {
+	return procSendARP.Find()
+}
+
+// Send an ARP request for the target with SendARP and return the MAC address of the reply (nil = none).
+// SendARP waits for the reply with its own timeout, so the pinger's timeout does not apply.
+func (p *Pinger) arpRequest(iface *net.Interface, source, target net.IP) (net.HardwareAddr, error) {
+	mac := make([]byte, 8)
+	size := uint32(len(mac))
+	// IPAddr holds the address bytes in network order
+	dst := binary.LittleEndian.Uint32(target.To4())
+	src := binary.LittleEndian.Uint32(source.To4())
+	if !p.pace(ethernetHeaderLen + arpPacketLen) {
+		return nil, nil // Cancelled before the request went out
+	}
+	ret, _, _ := procSendARP.Call(uintptr(dst), uintptr(src), uintptr(unsafe.Pointer(&mac[0])), uintptr(unsafe.Pointer(&size)))
+	switch syscall.Errno(ret) {
+	case 0:
+		p.Breaker.record(false)
+		return net.HardwareAddr(mac[:size]), nil
+	case windows.ERROR_BAD_NET_NAME, windows.ERROR_GEN_FAILURE:
+		p.Breaker.record(false)
+		return nil, nil // No reply
+	}
+	p.Breaker.record(true)
+	return nil, syscall.Errno(ret)
+}
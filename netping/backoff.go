@@ -0,0 +1,45 @@
+package netping
+
+import (
+	"fmt"
+	"time"
+)
+
+// How long a Pinger waits between the attempts of a host; the base interval is half the timeout
+type Backoff int
+
+const (
+	BackoffFixed       Backoff = iota // Wait the base interval after every attempt
+	BackoffNone                       // Retry at once, for fast LANs
+	BackoffExponential                // Double the wait after every attempt: base, 2×base, 4×base, for flaky WAN links
+)
+
+// Backoff strategies by name, as accepted by ParseBackoff
+var backoffNames = map[string]Backoff{"fixed": BackoffFixed, "none": BackoffNone, "exp": BackoffExponential}
+
+// Parse a backoff strategy name: none, fixed, or exp
+func ParseBackoff(name string) (Backoff, error) {
+	backoff, ok := backoffNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown retry backoff '%s' (expected none, fixed, or exp)", name)
+	}
+	return backoff, nil
+}
+
+// Wait after the given 0-based attempt before the next one
+func (p *Pinger) retryDelay(attempt int) time.Duration {
+	base := p.Timeout / 2
+	switch p.Backoff {
+	case BackoffNone:
+		return 0
+	case BackoffExponential:
+		return base << min(attempt, 16) // Capped so the shift cannot overflow
+	}
+	return base
+}
+
+// Wait out the backoff after the given 0-based attempt of a probe made outside the package, such as an
+// HTTP request; false if the context was cancelled
+func (p *Pinger) WaitRetry(attempt int) bool {
+	return p.sleep(p.retryDelay(attempt))
+}
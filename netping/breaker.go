@@ -0,0 +1,94 @@
+package netping
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	breakerWindow     = 5 * time.Second // Window over which the send-error rate is measured
+	breakerMinSamples = 20              // Sends needed in the window before the breaker may trip
+)
+
+// Pauses sending when too many sends fail (e.g. "no buffer space available", "network unreachable"),
+// then resumes at a reduced rate
+type Breaker struct {
+	mu        sync.Mutex
+	threshold float64 // Fraction of failed sends that trips the breaker
+	cooldown  time.Duration
+	limiter   *Limiter
+	sends     []sendEvent // Sends within the window, oldest first
+	failures  int         // Failed sends among them, kept as they come and go so a send costs O(1)
+	openUntil time.Time   // Sending is paused until this time
+	trips     int
+	Log       *log.Logger // Where trips are reported
+}
+
+// Outcome of one send, kept for the error-rate window
+type sendEvent struct {
+	at     time.Time
+	failed bool
+}
+
+// Create a breaker that slows the limiter down each time it trips; a zero threshold disables it
+func NewBreaker(threshold float64, cooldown time.Duration, limiter *Limiter) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown, limiter: limiter, Log: log.Default()}
+}
+
+// Block while the breaker is open; returns false early if ctx is cancelled
+func (b *Breaker) wait(ctx context.Context) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	until := b.openUntil
+	b.mu.Unlock()
+	return sleepContext(ctx, time.Until(until))
+}
+
+// Record the outcome of a send and trip the breaker if the error rate over the window is too high
+func (b *Breaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.sends = append(b.sends, sendEvent{at: now, failed: failed})
+	if failed {
+		b.failures++
+	}
+	for len(b.sends) > 0 && now.Sub(b.sends[0].at) > breakerWindow {
+		if b.sends[0].failed {
+			b.failures--
+		}
+		b.sends = b.sends[1:]
+	}
+	if now.Before(b.openUntil) || len(b.sends) < breakerMinSamples {
+		return
+	}
+
+	if rate := float64(b.failures) / float64(len(b.sends)); rate >= b.threshold {
+		b.trips++
+		b.openUntil = now.Add(b.cooldown)
+		b.sends, b.failures = nil, 0
+		b.limiter.slowDown()
+		b.Log.Printf("Circuit breaker tripped: %.0f%% of sends failed in the last %s, pausing for %s and halving the send rate\n", rate*100, breakerWindow, b.cooldown)
+	}
+}
+
+// Number of times the breaker tripped
+func (b *Breaker) TripCount() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.trips
+}
@@ -0,0 +1,50 @@
+package netping
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestBreakerTripsOnFailureRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		failEvery int // Every nth send fails (0 = none)
+		wantTrips int
+	}{
+		{"no failures", 0, 0},
+		{"a third fail", 3, 0},
+		{"half fail", 2, 1},
+		{"all fail", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(0.5, time.Hour, NewLimiter(0, 0))
+			b.Log = log.New(io.Discard, "", 0)
+			for i := 1; i <= 2*breakerMinSamples; i++ {
+				b.record(tt.failEvery > 0 && i%tt.failEvery == 0)
+			}
+			if got := b.TripCount(); got != tt.wantTrips {
+				t.Errorf("trips = %d, want %d", got, tt.wantTrips)
+			}
+		})
+	}
+}
+
+func TestBreakerForgetsOldFailures(t *testing.T) {
+	b := NewBreaker(0.5, time.Hour, NewLimiter(0, 0))
+	b.Log = log.New(io.Discard, "", 0)
+	// Failures from before the window no longer count against the sends in it
+	old := time.Now().Add(-2 * breakerWindow)
+	for range breakerMinSamples {
+		b.sends = append(b.sends, sendEvent{at: old, failed: true})
+		b.failures++
+	}
+	for range breakerMinSamples {
+		b.record(false)
+	}
+	if b.TripCount() != 0 || b.failures != 0 || len(b.sends) != breakerMinSamples {
+		t.Errorf("trips = %d, failures = %d, sends = %d; want 0, 0, %d", b.TripCount(), b.failures, len(b.sends), breakerMinSamples)
+	}
+}
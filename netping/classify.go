@@ -0,0 +1,69 @@
+package netping
+
+import (
+	"math"
+	"time"
+)
+
+// Response tiers assigned by ClassifyHost
+const (
+	TierResponsive   = "responsive"   // Every probe answered with consistent RTT
+	TierIntermittent = "intermittent" // Some probes answered, or RTT varies widely
+	TierFiltered     = "filtered"     // No replies, but destination unreachable reported
+	TierSilent       = "silent"       // No response of any kind
+)
+
+// Tiers in the order they are tallied in the summary
+var Tiers = []string{TierResponsive, TierIntermittent, TierFiltered, TierSilent}
+
+// Probe a host several times and classify it by response behavior, returning the tier
+// and the first reply (Attempt 0 if none came back)
+func (p *Pinger) ClassifyHost(target string, probes int) (string, Reply) {
+	var rtts []time.Duration
+	var unreachable int
+	var first Reply
+	for i := 0; i < probes; i++ {
+		status, reply, _ := p.probe(target)
+		if status == probeFailed {
+			break // Later probes would fail the same way
+		}
+		switch status {
+		case probeReply:
+			rtts = append(rtts, reply.RTT)
+			if first.Attempt == 0 {
+				first, first.Attempt = reply, i+1
+			}
+		case probeUnreachable:
+			unreachable++
+		}
+	}
+	return classify(probes, unreachable, rtts), first
+}
+
+// Pick a tier from the probe outcomes
+func classify(probes, unreachable int, rtts []time.Duration) string {
+	if len(rtts) == 0 {
+		if unreachable > 0 {
+			return TierFiltered
+		}
+		return TierSilent
+	}
+	if len(rtts) < probes {
+		return TierIntermittent
+	}
+
+	// Replies whose RTT standard deviation exceeds half the mean are too jittery to call responsive
+	var sum float64
+	for _, rtt := range rtts {
+		sum += float64(rtt)
+	}
+	mean := sum / float64(len(rtts))
+	var variance float64
+	for _, rtt := range rtts {
+		variance += (float64(rtt) - mean) * (float64(rtt) - mean)
+	}
+	if math.Sqrt(variance/float64(len(rtts))) > mean/2 {
+		return TierIntermittent
+	}
+	return TierResponsive
+}
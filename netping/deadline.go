@@ -0,0 +1,49 @@
+package netping
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Shrinks the retries per host as a scan's time budget runs out, so every host gets at least one probe
+type RetryBudget struct {
+	deadline    time.Time
+	concurrency int
+	pending     func() int32 // Hosts not probed yet
+	reduced     int32        // Hosts that got fewer attempts than configured
+}
+
+// Create a retry budget for a scan that should finish within the given duration (0 = no budget)
+func NewRetryBudget(budget time.Duration, concurrency int, pending func() int32) *RetryBudget {
+	if budget <= 0 {
+		return nil
+	}
+	return &RetryBudget{deadline: time.Now().Add(budget), concurrency: concurrency, pending: pending}
+}
+
+// Return how many of the configured attempts a host may use, given the time left and the
+// hosts still waiting; every attempt may wait the timeout for its reply, and every attempt but the last the retry delay
+func (b *RetryBudget) attempts(configured int, timeout, delay time.Duration) int {
+	if b == nil {
+		return configured
+	}
+	// Each concurrency slot still has to get through its share of the pending hosts
+	waves := int64(b.pending())/int64(b.concurrency) + 1
+	allowed := int((int64(time.Until(b.deadline))/waves + int64(delay)) / int64(timeout+delay))
+	if allowed >= configured {
+		return configured
+	}
+	atomic.AddInt32(&b.reduced, 1)
+	if allowed < 1 {
+		return 1
+	}
+	return allowed
+}
+
+// Number of hosts that got reduced retries
+func (b *RetryBudget) ReducedCount() int32 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt32(&b.reduced)
+}
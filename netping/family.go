@@ -0,0 +1,119 @@
+package netping
+
+import (
+	"net"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP socket and message types of one IP version
+type icmpFamily struct {
+	network     string // Raw socket network for icmp.ListenPacket
+	datagram    string // Unprivileged datagram socket network, used without raw socket privileges
+	protocol    int    // IANA protocol number used to parse replies
+	headerLen   int    // IP header length counted by the rate limiter
+	echoRequest icmp.Type
+	echoReply   icmp.Type
+	unreachable icmp.Type
+	expired     icmp.Type // Time exceeded: the TTL (hop limit) ran out in transit
+}
+
+var (
+	icmpv4 = icmpFamily{"ip4:icmp", "udp4", 1, ipv4.HeaderLen, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply, ipv4.ICMPTypeDestinationUnreachable, ipv4.ICMPTypeTimeExceeded}
+	icmpv6 = icmpFamily{"ip6:ipv6-icmp", "udp6", 58, ipv6.HeaderLen, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, ipv6.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeTimeExceeded}
+)
+
+// Pick the ICMP family for a target address
+func familyOf(ip net.IP) icmpFamily {
+	if ip.To4() != nil {
+		return icmpv4
+	}
+	return icmpv6
+}
+
+// Local address to bind for a family; an IPv4 source cannot send IPv6 probes, so those use any address
+func (f icmpFamily) bindAddress(source string) string {
+	if ip := net.ParseIP(source); ip != nil && (ip.To4() != nil) != (f == icmpv4) {
+		return ""
+	}
+	return source
+}
+
+// Ask the socket to report the TTL (IPv6 hop limit) of each packet it receives; unsupported on some platforms
+func (f icmpFamily) enableTTL(conn *icmp.PacketConn) {
+	if f == icmpv4 {
+		conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	} else {
+		conn.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	}
+}
+
+// Set the TTL (IPv6 hop limit) of the packets the socket sends; 0 keeps the OS default
+func (f icmpFamily) setTTL(conn *icmp.PacketConn, ttl int) error {
+	if ttl == 0 {
+		return nil
+	}
+	if f == icmpv4 {
+		return conn.IPv4PacketConn().SetTTL(ttl)
+	}
+	return conn.IPv6PacketConn().SetHopLimit(ttl)
+}
+
+// Read one ICMP message and the TTL it arrived with (0 = unknown)
+func (f icmpFamily) readFrom(conn *icmp.PacketConn, buffer []byte) (int, net.Addr, int, error) {
+	if f == icmpv4 {
+		n, cm, peer, err := conn.IPv4PacketConn().ReadFrom(buffer)
+		if cm == nil {
+			return n, peer, 0, err
+		}
+		return n, peer, cm.TTL, err
+	}
+	n, cm, peer, err := conn.IPv6PacketConn().ReadFrom(buffer)
+	if cm == nil {
+		return n, peer, 0, err
+	}
+	return n, peer, cm.HopLimit, err
+}
+
+// Address of the sender of a packet read from a raw or datagram ICMP socket
+func peerIP(peer net.Addr) (net.IP, bool) {
+	switch addr := peer.(type) {
+	case *net.IPAddr:
+		return addr.IP, true
+	case *net.UDPAddr:
+		return addr.IP, true
+	}
+	return nil, false
+}
+
+// Check whether an ICMP error quotes an echo request we sent to the target
+func (f icmpFamily) quotesEcho(data []byte, target net.IP, id int) bool {
+	dst, quotedID, _, ok := f.quotedEcho(data)
+	return ok && dst.Equal(target) && quotedID == id
+}
+
+// Extract the destination, ID and sequence number of the echo request quoted in an ICMP error
+func (f icmpFamily) quotedEcho(data []byte) (net.IP, int, int, bool) {
+	var dst net.IP
+	var inner []byte
+	var echoType byte
+	if f == icmpv4 {
+		header, err := ipv4.ParseHeader(data)
+		if err != nil || len(data) < header.Len+ICMPHeaderLen {
+			return nil, 0, 0, false
+		}
+		dst, inner, echoType = header.Dst, data[header.Len:], byte(ipv4.ICMPTypeEcho)
+	} else {
+		header, err := ipv6.ParseHeader(data)
+		if err != nil || len(data) < ipv6.HeaderLen+ICMPHeaderLen {
+			return nil, 0, 0, false
+		}
+		dst, inner, echoType = header.Dst, data[ipv6.HeaderLen:], byte(ipv6.ICMPTypeEchoRequest)
+	}
+	if inner[0] != echoType {
+		return nil, 0, 0, false
+	}
+	return dst, int(inner[4])<<8 | int(inner[5]), int(inner[6])<<8 | int(inner[7]), true
+}
@@ -0,0 +1,125 @@
+package netping
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// Sequence number of the last fragmented probe; each probe gets its own so a late reply to an
+// earlier attempt is never taken for the current one
+var fragmentSeq uint32
+
+// IPv4 identification shared by the fragments of one probe
+var fragmentID uint32
+
+// Send an echo request split into two IP fragments and report whether the target reassembled it and replied.
+// Writing our own IP headers requires a raw socket with IP_HDRINCL (root/CAP_NET_RAW on Linux).
+func (p *Pinger) probeFragmented(target string) bool {
+	targetIP := net.ParseIP(target).To4()
+	if targetIP == nil {
+		p.Log.Printf("Invalid target IP: %s\n", target)
+		return false
+	}
+
+	conn, err := net.ListenPacket("ip4:icmp", p.source)
+	if err != nil {
+		p.Log.Printf("Error creating ICMP connection: %v\n", err)
+		return false
+	}
+	defer conn.Close()
+	rawConn, err := ipv4.NewRawConn(conn)
+	if err != nil {
+		p.Log.Printf("Error creating raw IP connection: %v\n", err)
+		return false
+	}
+
+	// Create ICMP echo request
+	id := os.Getpid() & 0xffff
+	seq := int(atomic.AddUint32(&fragmentSeq, 1) & 0xffff)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: p.payload},
+	}
+	msgBytes, err := msg.Marshal(nil)
+	if err != nil {
+		p.Log.Printf("Error marshaling ICMP message: %v\n", err)
+		return false
+	}
+
+	// Split the message roughly in half; every fragment but the last must carry a multiple of 8 bytes
+	split := (len(msgBytes) / 2) &^ 7
+	if split == 0 {
+		split = 8
+	}
+	ipID := int(atomic.AddUint32(&fragmentID, 1) & 0xffff)
+	fragments := []struct {
+		data  []byte
+		flags ipv4.HeaderFlags
+	}{
+		{msgBytes[:split], ipv4.MoreFragments},
+		{msgBytes[split:], 0},
+	}
+
+	sent := time.Now()
+	for i, fragment := range fragments {
+		header := &ipv4.Header{
+			Version:  ipv4.Version,
+			Len:      ipv4.HeaderLen,
+			TotalLen: ipv4.HeaderLen + len(fragment.data),
+			ID:       ipID,
+			Flags:    fragment.flags,
+			FragOff:  i * split / 8, // Offset in 8-byte units
+			TTL:      64,
+			Protocol: 1, // ICMP
+			Dst:      targetIP,
+		}
+		if !p.pace(header.TotalLen) {
+			return false
+		}
+		err := rawConn.WriteTo(header, fragment.data, nil)
+		p.Breaker.record(err != nil)
+		if err != nil {
+			p.Log.Printf("Error sending fragmented ICMP request to %s: %v\n", target, err)
+			return false
+		}
+	}
+
+	// Wait for the reply to the reassembled request
+	rawConn.SetReadDeadline(sent.Add(p.Timeout))
+	buffer := make([]byte, 1500+len(msgBytes))
+	for {
+		header, payload, _, err := rawConn.ReadFrom(buffer)
+		if err != nil {
+			return false
+		}
+		if !header.Src.Equal(targetIP) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(ipv4.ICMPTypeEchoReply.Protocol(), payload)
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == id && echo.Seq == seq && bytes.Equal(echo.Data, p.payload) {
+			return true
+		}
+	}
+}
+
+// Retry the fragmented probe like a plain one
+func (p *Pinger) IsFragmentedAliveWithRetries(target string) bool {
+	for i := 0; i < p.Retries; i++ {
+		if p.probeFragmented(target) {
+			return true
+		}
+		if i < p.Retries-1 && !p.sleep(p.retryDelay(i)) { // Wait before retrying, but not after the last attempt
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package netping
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// Shared ICMP sockets of a pinger, one per IP version, opened on first use
+type socketPool struct {
+	mu       sync.Mutex
+	source   string
+	datagram bool                // Skip raw sockets and open unprivileged datagram sockets
+	ttl      int                 // TTL (IPv6 hop limit) of outgoing echo requests (0 = OS default)
+	muxes    map[string]*icmpMux // By raw socket network
+}
+
+// Return the shared socket for a family, opening it if needed
+func (pool *socketPool) get(family icmpFamily) (*icmpMux, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if mux, ok := pool.muxes[family.network]; ok {
+		return mux, nil
+	}
+	conn, datagram, err := pool.listen(family)
+	if err != nil {
+		return nil, err
+	}
+	family.enableTTL(conn)
+	if err := family.setTTL(conn, pool.ttl); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot set TTL %d: %w", pool.ttl, err)
+	}
+	mux := &icmpMux{
+		conn:     conn,
+		family:   family,
+		datagram: datagram,
+		id:       (os.Getpid() ^ 0x8000) & 0xffff, // Differs from the ID of sweep, fragment and DF probes
+		pending:  map[int]*echoRequest{},
+	}
+	if datagram {
+		mux.id = conn.LocalAddr().(*net.UDPAddr).Port // The kernel rewrites the echo ID to the socket's port
+	}
+	if pool.muxes == nil {
+		pool.muxes = map[string]*icmpMux{}
+	}
+	pool.muxes[family.network] = mux
+	go mux.read()
+	return mux, nil
+}
+
+// Open a raw ICMP socket, falling back to an unprivileged datagram socket when raw sockets are not permitted
+func (pool *socketPool) listen(family icmpFamily) (*icmp.PacketConn, bool, error) {
+	address := family.bindAddress(pool.source)
+	var rawErr error
+	if !pool.datagram {
+		conn, err := icmp.ListenPacket(family.network, address)
+		if err == nil || !errors.Is(err, os.ErrPermission) {
+			return conn, false, err
+		}
+		rawErr = err
+	}
+	conn, err := icmp.ListenPacket(family.datagram, address)
+	if err != nil && rawErr != nil {
+		return nil, false, fmt.Errorf("%w; unprivileged datagram socket: %v", rawErr, err)
+	}
+	return conn, err == nil, err
+}
+
+// Close every shared socket, stopping their readers
+func (pool *socketPool) close() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for network, mux := range pool.muxes {
+		mux.conn.Close()
+		delete(pool.muxes, network)
+	}
+}
+
+// Reply or error matched to an outstanding echo request
+type probeOutcome struct {
+	status probeStatus
+	at     time.Time
+	ttl    int // TTL of the reply as received (0 = unknown)
+	seq    int // Sequence number of the request it answers
+}
+
+// Outstanding echo request: where it went, the payload its reply must echo back, and who is waiting for it
+type echoRequest struct {
+	target   net.IP
+	payload  []byte
+	notify   func(probeOutcome) // Called once, by the socket's reader, with the first matching reply or error
+	answered bool
+}
+
+// One ICMP socket shared by concurrent probes; replies are matched to requests by echo sequence number
+type icmpMux struct {
+	conn     *icmp.PacketConn
+	family   icmpFamily
+	datagram bool // Unprivileged datagram socket, which only receives replies to its own requests
+	id       int
+	mu       sync.Mutex
+	seq      int                  // Last sequence number handed out
+	pending  map[int]*echoRequest // Outstanding requests by sequence number
+}
+
+// Destination address of a request in the form the socket expects
+func (m *icmpMux) addr(ip net.IP) net.Addr {
+	if m.datagram {
+		return &net.UDPAddr{IP: ip}
+	}
+	return &net.IPAddr{IP: ip}
+}
+
+// Every sequence number is awaiting a reply; the request can be retried once one is released
+var errNoFreeSeq = errors.New("no free ICMP sequence number")
+
+// Reserve a sequence number for a request carrying the payload to the target; notify must not block,
+// since the socket's reader calls it
+func (m *icmpMux) register(target net.IP, payload []byte, notify func(probeOutcome)) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for range 0x10000 {
+		m.seq = (m.seq + 1) & 0xffff
+		if _, busy := m.pending[m.seq]; !busy {
+			m.pending[m.seq] = &echoRequest{target: target, payload: payload, notify: notify}
+			return m.seq, nil
+		}
+	}
+	return -1, errNoFreeSeq
+}
+
+// Release a sequence number once its request is answered or timed out
+func (m *icmpMux) unregister(seq int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, seq)
+}
+
+// Deliver an outcome to the request with the given sequence number, if it was sent to the given address;
+// a reply must also echo the request's payload unchanged, so mangled or spoofed replies are ignored
+func (m *icmpMux) deliver(seq int, from net.IP, status probeStatus, ttl int, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	request, ok := m.pending[seq]
+	if ok && !request.answered && request.target.Equal(from) && (status != probeReply || bytes.Equal(data, request.payload)) {
+		request.answered = true // Duplicate replies are ignored
+		request.notify(probeOutcome{status, time.Now(), ttl, seq})
+	}
+}
+
+// Match incoming replies and errors to outstanding requests until the socket is closed
+func (m *icmpMux) read() {
+	buffer := make([]byte, 65535)
+	for {
+		n, peer, ttl, err := m.family.readFrom(m.conn, buffer)
+		if err != nil {
+			return
+		}
+		msg, err := icmp.ParseMessage(m.family.protocol, buffer[:n])
+		if err != nil {
+			continue
+		}
+		switch msg.Type {
+		case m.family.echoReply:
+			echo, ok := msg.Body.(*icmp.Echo)
+			from, isIP := peerIP(peer)
+			if ok && isIP && echo.ID == m.id {
+				m.deliver(echo.Seq, from, probeReply, ttl, echo.Data)
+			}
+		case m.family.unreachable:
+			// The error quotes our original request, including its destination, ID and sequence number
+			if body, ok := msg.Body.(*icmp.DstUnreach); ok {
+				if dst, id, seq, ok := m.family.quotedEcho(body.Data); ok && id == m.id {
+					m.deliver(seq, dst, probeUnreachable, 0, nil)
+				}
+			}
+		case m.family.expired:
+			if body, ok := msg.Body.(*icmp.TimeExceeded); ok {
+				if dst, id, seq, ok := m.family.quotedEcho(body.Data); ok && id == m.id {
+					m.deliver(seq, dst, probeExpired, 0, nil)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package netping
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRegisterFullSequenceSpace(t *testing.T) {
+	m := &icmpMux{pending: map[int]*echoRequest{}}
+	target := net.ParseIP("10.0.0.1")
+	for range 0x10000 {
+		if _, err := m.register(target, nil, func(probeOutcome) {}); err != nil {
+			t.Fatalf("register with free sequence numbers: %v", err)
+		}
+	}
+	if _, err := m.register(target, nil, func(probeOutcome) {}); !errors.Is(err, errNoFreeSeq) {
+		t.Fatalf("register with every sequence number pending: err = %v, want %v", err, errNoFreeSeq)
+	}
+
+	m.unregister(1234)
+	if seq, err := m.register(target, nil, func(probeOutcome) {}); err != nil || seq != 1234 {
+		t.Errorf("register after a release = %d, %v, want 1234", seq, err)
+	}
+}
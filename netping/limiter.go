@@ -0,0 +1,157 @@
+package netping
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter paces outgoing probes by packet rate and, optionally, by bandwidth
+type Limiter struct {
+	mu        sync.Mutex
+	rate      int          // Packets per second (0 = no packet rate cap)
+	bandwidth int64        // Maximum bits per second (0 = no bandwidth cap)
+	next      time.Time    // Earliest time the next packet may be sent
+	profile   *RateProfile // Time-of-day rates that override rate (nil = fixed rate)
+	backoff   int          // Times the rate was halved after send failures
+	jitter    float64      // Random spread of each gap as a fraction of it, 0-1 (0 = even spacing)
+}
+
+// Create a limiter from a packet rate (packets/second) and a bandwidth (bits/second)
+func NewLimiter(rate int, bandwidth int64) *Limiter {
+	return &Limiter{rate: rate, bandwidth: bandwidth}
+}
+
+// Packet rate in effect at the given time, after the rate profile and any backoff (0 = no cap)
+func (l *Limiter) rateAt(now time.Time) int {
+	rate := l.rate
+	if l.profile != nil {
+		if profileRate, ok := l.profile.rateAt(now); ok {
+			rate = profileRate
+		}
+	}
+	if l.backoff > 0 {
+		if rate == 0 {
+			rate = DefaultRate // Unlimited scans fall back to the default rate
+		}
+		rate = max(rate>>l.backoff, 1)
+	}
+	return rate
+}
+
+// Packet rate currently in effect (0 = no cap)
+func (l *Limiter) CurrentRate() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rateAt(time.Now())
+}
+
+// Block until a packet of the given size (in bytes, headers included) may be sent; returns false early
+// if ctx is cancelled, so a scan that is stopped does not wait out the slots already reserved
+func (l *Limiter) Wait(ctx context.Context, packetSize int) bool {
+	l.mu.Lock()
+	now := time.Now()
+	// Use whichever of the packet rate and the bandwidth cap is more restrictive
+	var gap time.Duration
+	if rate := l.rateAt(now); rate > 0 {
+		gap = time.Second / time.Duration(rate)
+	}
+	if l.bandwidth > 0 {
+		if bwGap := time.Duration(int64(packetSize) * 8 * int64(time.Second) / l.bandwidth); bwGap > gap {
+			gap = bwGap
+		}
+	}
+	if l.jitter > 0 {
+		// Spread uniformly around the gap, so the average rate stays the configured one
+		gap = time.Duration(float64(gap) * (1 + l.jitter*(2*rand.Float64()-1)))
+	}
+	sendAt := l.next
+	if sendAt.Before(now) {
+		sendAt = now
+	}
+	l.next = sendAt.Add(gap)
+	l.mu.Unlock()
+
+	return sleepContext(ctx, time.Until(sendAt))
+}
+
+// Change the base packet rate
+func (l *Limiter) SetRate(rate int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+}
+
+// Apply time-of-day rates that override the base rate (nil = fixed rate)
+func (l *Limiter) SetProfile(profile *RateProfile) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.profile = profile
+}
+
+// Vary each gap between packets randomly by up to the given fraction of it (0.3 = ±30%), so probes
+// do not leave with a machine-regular cadence; 0 spaces them evenly
+func (l *Limiter) SetJitter(fraction float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.jitter = fraction
+}
+
+// Halve the packet rate and bandwidth after repeated send failures
+func (l *Limiter) slowDown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backoff++
+	if l.bandwidth > 0 {
+		l.bandwidth = max(l.bandwidth/2, 1) // A cap halved to 0 would lift it
+	}
+}
+
+// Parse a bandwidth string such as "1mbps", "500kbps" or "64000" into bits per second
+func ParseBandwidth(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		mult   float64
+	}{
+		{"gbps", 1e9}, {"mbps", 1e6}, {"kbps", 1e3}, {"bps", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSuffix(s, unit.suffix)
+			multiplier = unit.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q (expected e.g. 1mbps, 500kbps)", value)
+	}
+	return int64(n * multiplier), nil
+}
+
+// Parse a jitter such as "30%", "±30%" or "0.3" into a fraction of the gap between packets, 0-1
+func ParseJitter(value string) (float64, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(value), "±")
+	if s == "" {
+		return 0, nil
+	}
+	percent := strings.HasSuffix(s, "%")
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if percent {
+		n /= 100
+	}
+	if err != nil || !(n >= 0 && n <= 1) { // Also rejects NaN
+		return 0, fmt.Errorf("invalid jitter %q (expected a percentage from 0%% to 100%%, e.g. 30%%)", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package netping
+
+import "testing"
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"64000", 64000, false},
+		{"1mbps", 1000000, false},
+		{"1.5Mbps", 1500000, false},
+		{"500kbps", 500000, false},
+		{" 2 gbps ", 2000000000, false},
+		{"800bps", 800, false},
+		{"0", 0, true},
+		{"-1mbps", 0, true},
+		{"mbps", 0, true},
+		{"fast", 0, true},
+		{"10mb", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBandwidth(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("bandwidth = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJitter(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30%", 0.3, false},
+		{"±30%", 0.3, false},
+		{"0.25", 0.25, false},
+		{"0%", 0, false},
+		{"100%", 1, false},
+		{"150%", 0, true},
+		{"1.5", 0, true},
+		{"-10%", 0, true},
+		{"NaN", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseJitter(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("jitter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlowDownKeepsBandwidthCap(t *testing.T) {
+	tests := []struct {
+		bandwidth int64
+		want      int64
+	}{
+		{0, 0}, // Uncapped stays uncapped
+		{1000, 500},
+		{3, 1},
+		{1, 1},
+	}
+	for _, tt := range tests {
+		l := NewLimiter(0, tt.bandwidth)
+		l.slowDown()
+		if l.bandwidth != tt.want {
+			t.Errorf("bandwidth %d slowed down to %d, want %d", tt.bandwidth, l.bandwidth, tt.want)
+		}
+	}
+}
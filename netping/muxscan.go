@@ -0,0 +1,320 @@
+package netping
+
+import (
+	"container/heap"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// Requests a multiplexed scan may have awaiting a reply, leaving half of the 16-bit sequence space to other probes
+const maxInFlight = 0x8000
+
+// Probe every target, an IP address, without a goroutine per host: one sender loop sends echo requests
+// from the shared socket at the limiter's pace, the socket's reader matches replies to outstanding
+// requests by sequence number, and a sweeper expires requests unanswered after the timeout, queueing
+// the host's next attempt after the backoff. Results are sent as targets finish; the channel is closed
+// once every target is done, or soon after the pinger's context is cancelled. Only plain echo requests
+// are covered, with the pinger's Timeout for every target; probes that take several steps per host, such
+// as ClassifyHost, PingStats or DiscoverPathMTU, still need a goroutine per host.
+func (p *Pinger) Scan(targets []string) <-chan Result {
+	results := make(chan Result)
+	s := &muxScan{
+		p:         p,
+		remaining: len(targets),
+		wake:      make(chan struct{}, 1),
+		retries:   make(chan *flight),
+		slots:     make(chan struct{}, maxInFlight),
+		stop:      make(chan struct{}),
+	}
+	go s.send(targets)
+	go s.sweep(results)
+	return results
+}
+
+// Host being probed by a multiplexed scan
+type flight struct {
+	result   Result
+	ip       net.IP
+	mux      *icmpMux
+	attempts int       // Attempts the host may use
+	attempt  int       // Attempts sent so far
+	seq      int       // Sequence number of the outstanding request (-1 = none)
+	sent     time.Time // When the outstanding request was sent
+}
+
+// Kinds of events the sender and the socket's reader pass to the sweeper
+type muxEventKind int
+
+const (
+	muxSent        muxEventKind = iota // Request is about to go out
+	muxSendFailed                      // Request could not be sent (err is set)
+	muxOutcome                         // Reply or error matched to a request
+	muxUnprobeable                     // Target cannot be probed at all (result.Err is set)
+)
+
+// Event in the life of a flight
+type muxEvent struct {
+	kind    muxEventKind
+	f       *flight
+	seq     int
+	outcome probeOutcome
+	err     error
+}
+
+// State of one multiplexed scan
+type muxScan struct {
+	p         *Pinger
+	mu        sync.Mutex
+	inbox     []muxEvent    // Events not yet handled by the sweeper
+	wake      chan struct{} // Signals new events in the inbox
+	retries   chan *flight  // Hosts due another attempt, handed from the sweeper to the sender
+	slots     chan struct{} // Semaphore of requests awaiting a reply
+	stop      chan struct{} // Closed when the scan is over
+	remaining int           // Targets not finished yet; only touched by the sweeper
+}
+
+// Queue an event for the sweeper; never blocks, so the socket's reader can call it
+func (s *muxScan) post(event muxEvent) {
+	s.mu.Lock()
+	s.inbox = append(s.inbox, event)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default: // Already signalled
+	}
+}
+
+// Send the first attempt of every target in order, and further attempts as the sweeper hands them back
+func (s *muxScan) send(targets []string) {
+	next := 0
+	for {
+		var f *flight
+		if next < len(targets) {
+			select {
+			case f = <-s.retries:
+			case <-s.stop:
+				return
+			default:
+				f = &flight{result: Result{Target: targets[next]}, seq: -1}
+				next++
+			}
+		} else {
+			select {
+			case f = <-s.retries:
+			case <-s.stop:
+				return
+			}
+		}
+		if f.mux == nil && !s.open(f) {
+			continue
+		}
+		select {
+		case s.slots <- struct{}{}: // Acquire an in-flight slot, released by the sweeper
+		case <-s.stop:
+			return
+		}
+		s.transmit(f)
+	}
+}
+
+// Parse a target and get its family's shared socket before its first attempt; false if it cannot be probed
+func (s *muxScan) open(f *flight) bool {
+	f.ip = net.ParseIP(f.result.Target)
+	if f.ip == nil {
+		f.result.Err = &PermanentError{Err: fmt.Errorf("invalid target IP: %s", f.result.Target)}
+		s.post(muxEvent{kind: muxUnprobeable, f: f})
+		return false
+	}
+	f.result.IP = f.ip.String()
+	mux, err := s.p.sockets.get(familyOf(f.ip))
+	if err != nil {
+		f.result.Err = &PermanentError{Err: fmt.Errorf("cannot open an ICMP socket: %w", err)}
+		s.post(muxEvent{kind: muxUnprobeable, f: f})
+		return false
+	}
+	f.mux = mux
+	return true
+}
+
+// Send one echo request for a host, telling the sweeper before it goes out so a fast reply finds it registered
+func (s *muxScan) transmit(f *flight) {
+	if !s.p.pace(f.mux.family.headerLen + ICMPHeaderLen + len(s.p.payload)) { // Account for IP header + ICMP message
+		return // Cancelled; the sweeper has stopped, and the host is dropped with it
+	}
+	seq, err := f.mux.register(f.ip, s.p.payload, func(outcome probeOutcome) {
+		s.post(muxEvent{kind: muxOutcome, f: f, seq: outcome.seq, outcome: outcome})
+	})
+	s.post(muxEvent{kind: muxSent, f: f, seq: seq, outcome: probeOutcome{at: time.Now()}})
+	if err != nil {
+		// Counts as an attempt that failed to send, so the host is retried after the backoff
+		s.p.Log.Printf("Error sending ICMP request to %s: %v\n", f.result.IP, err)
+		s.post(muxEvent{kind: muxSendFailed, f: f, seq: seq, err: err})
+		return
+	}
+	msg := icmp.Message{
+		Type: f.mux.family.echoRequest, Code: 0,
+		Body: &icmp.Echo{ID: f.mux.id, Seq: seq, Data: s.p.payload},
+	}
+	msgBytes, err := msg.Marshal(nil)
+	if err == nil {
+		_, err = f.mux.conn.WriteTo(msgBytes, f.mux.addr(f.ip))
+	}
+	s.p.Breaker.record(err != nil)
+	if err != nil {
+		s.p.Log.Printf("Error sending ICMP request to %s: %v\n", f.result.IP, err)
+		s.post(muxEvent{kind: muxSendFailed, f: f, seq: seq, err: err})
+	}
+}
+
+// Handle the events of every flight, expire unanswered requests and schedule retries until every target is done
+func (s *muxScan) sweep(results chan<- Result) {
+	defer close(results)
+	defer close(s.stop)
+
+	var timers timerHeap
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	var due []*flight // Hosts whose backoff is over, waiting for the sender
+	var done []Result // Finished results waiting for the receiver
+	for s.remaining > 0 || len(done) > 0 {
+		var retries chan<- *flight
+		var nextRetry *flight
+		if len(due) > 0 {
+			retries, nextRetry = s.retries, due[0]
+		}
+		var out chan<- Result
+		var nextResult Result
+		if len(done) > 0 {
+			out, nextResult = results, done[0]
+		}
+		if len(timers) > 0 {
+			timer.Reset(time.Until(timers[0].at))
+		}
+
+		select {
+		case <-s.wake:
+			s.mu.Lock()
+			events := s.inbox
+			s.inbox = nil
+			s.mu.Unlock()
+			for _, event := range events {
+				done = s.handle(event, &timers, done)
+			}
+		case <-timer.C:
+			for len(timers) > 0 && !timers[0].at.After(time.Now()) {
+				t := heap.Pop(&timers).(flightTimer)
+				if t.retry {
+					due = append(due, t.f)
+				} else if t.f.seq == t.seq {
+					done = s.fail(t.f, &timers, done) // Timed out
+				}
+			}
+		case retries <- nextRetry:
+			due = due[1:]
+		case out <- nextResult:
+			done = done[1:]
+		case <-s.p.Context.Done():
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// Apply one event to its flight, returning the finished results
+func (s *muxScan) handle(event muxEvent, timers *timerHeap, done []Result) []Result {
+	f := event.f
+	switch event.kind {
+	case muxUnprobeable:
+		return s.finish(f, done)
+	case muxSent:
+		if f.attempt == 0 {
+			f.attempts = s.p.Budget.attempts(s.p.Retries, s.p.Timeout, s.p.retryDelay(0))
+		}
+		f.attempt++
+		f.seq, f.sent = event.seq, event.outcome.at
+		if f.seq >= 0 { // No request went out without a sequence number, so nothing expires
+			heap.Push(timers, flightTimer{at: f.sent.Add(s.p.Timeout), f: f, seq: f.seq})
+		}
+	case muxSendFailed:
+		if f.seq != event.seq {
+			return done
+		}
+		if permanentSendError(event.err) {
+			f.result.Err = &PermanentError{Err: fmt.Errorf("cannot send ICMP request to %s: %w", f.result.IP, event.err)}
+			s.release(f)
+			return s.finish(f, done) // Retrying would fail the same way
+		}
+		return s.fail(f, timers, done)
+	case muxOutcome:
+		if f.seq != event.seq {
+			return done // Reply to an attempt that already timed out
+		}
+		if event.outcome.status != probeReply {
+			return s.fail(f, timers, done)
+		}
+		f.result.Alive, f.result.Attempt = true, f.attempt
+		f.result.RTT, f.result.TTL = event.outcome.at.Sub(f.sent), event.outcome.ttl
+		s.release(f)
+		return s.finish(f, done)
+	}
+	return done
+}
+
+// End an attempt that got no reply: retry after the backoff, or give up once the host's attempts are used.
+// A host cut short by a cancelled scan is dropped rather than reported offline, as its probes said nothing
+func (s *muxScan) fail(f *flight, timers *timerHeap, done []Result) []Result {
+	s.release(f)
+	if s.p.Context.Err() != nil {
+		s.remaining--
+		return done
+	}
+	if f.attempt < f.attempts {
+		heap.Push(timers, flightTimer{at: time.Now().Add(s.p.retryDelay(f.attempt - 1)), f: f, retry: true})
+		return done
+	}
+	return s.finish(f, done)
+}
+
+// Free the sequence number and in-flight slot of a host's outstanding request
+func (s *muxScan) release(f *flight) {
+	f.mux.unregister(f.seq)
+	f.seq = -1
+	<-s.slots
+}
+
+// Queue a host's result for the receiver
+func (s *muxScan) finish(f *flight, done []Result) []Result {
+	s.remaining--
+	return append(done, f.result)
+}
+
+// When an outstanding request expires, or a host's backoff ends
+type flightTimer struct {
+	at    time.Time
+	f     *flight
+	seq   int  // Request that expires
+	retry bool // Backoff ends rather than a request expiring
+}
+
+// Timers of a multiplexed scan, earliest first
+type timerHeap []flightTimer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h timerHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *timerHeap) Push(x any)        { *h = append(*h, x.(flightTimer)) }
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,196 @@
+// Package netping sends ICMP echo probes with retries, rate limiting and a circuit breaker.
+// It is the probe engine of the NetPing CLI and can be embedded in other programs.
+// Raw ICMP sockets need root/Administrator (CAP_NET_RAW on Linux); without them, echo requests are
+// sent from unprivileged datagram sockets where the platform allows it.
+package netping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetries     = 3                 // Attempts for each host
+	DefaultConcurrency = 100               // Hosts probed at once by ScanHosts
+	DefaultTimeout     = 2 * time.Second   // Wait for each reply
+	DefaultRate        = 100               // Probes sent per second
+	Signature          = "HELLO-R-U-THERE" // Start of every echo payload
+	MaxPayloadSize     = 65507             // Largest ICMP payload that fits in an IPv4 packet
+	ICMPHeaderLen      = 8                 // Type, code, checksum, ID and sequence number of an echo message
+)
+
+// Settings of Ping and ScanHosts; the zero value uses the defaults
+type Options struct {
+	Timeout     time.Duration // How long to wait for each reply (0 = DefaultTimeout)
+	Retries     int           // Attempts per host (0 = DefaultRetries)
+	Rate        int           // Probes sent per second (0 = DefaultRate, negative = unlimited)
+	Bandwidth   int64         // Maximum bits per second (0 = no cap)
+	Jitter      float64       // Random spread of the gap between probes as a fraction of it, 0-1 (0 = even spacing)
+	Payload     string        // Start of every echo payload, to spot the probes in captures ("" = Signature)
+	PayloadSize int           // Echo payload size in bytes, padding or truncating Payload (0 = length of Payload)
+	Source      string        // Local address to send from ("" = any)
+	Concurrency int           // Hosts probed at once by ScanHosts (0 = DefaultConcurrency)
+	Datagram    bool          // Always use unprivileged datagram sockets (raw sockets are tried first otherwise)
+	TTL         int           // TTL (IPv6 hop limit) of echo requests, 1-255 (0 = OS default)
+	Backoff     Backoff       // Wait between attempts (BackoffFixed = half a timeout)
+	OnResult    func(Result)  // Called with every result as its target finishes, one call at a time (nil = none)
+}
+
+// Outcome of probing one target
+type Result struct {
+	Target  string        // Target as given: an IP address or a host name
+	IP      string        // Address probed ("" if the host name did not resolve)
+	Alive   bool          // An echo reply came back
+	Attempt int           // 1-based attempt that got a reply (0 if none did)
+	RTT     time.Duration // Round-trip time of the successful attempt
+	TTL     int           // TTL (IPv6 hop limit) the reply arrived with (0 = unknown)
+	Err     error         // Why the target could not be probed, or ctx.Err() if the probe was cut short
+}
+
+// Probe one target, an IP address or a host name, with retries
+func Ping(ctx context.Context, target string, opts Options) (Result, error) {
+	p := opts.newPinger(ctx)
+	defer p.Close()
+	result := p.ping(target)
+	opts.resultHook()(result)
+	return result, result.Err
+}
+
+// Probe every target concurrently from shared sockets, sending each result as soon as it is known.
+// The channel is closed once every target is done, or soon after ctx is cancelled.
+func ScanHosts(ctx context.Context, targets []string, opts Options) <-chan Result {
+	p := opts.newPinger(ctx)
+	onResult := opts.resultHook()
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		defer p.Close()
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for _, target := range targets {
+			select {
+			case sem <- struct{}{}: // Acquire a semaphore slot
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(target string) {
+				defer wg.Done()
+				defer func() { <-sem }() // Release the semaphore slot
+				result := p.ping(target)
+				onResult(result)
+				select {
+				case results <- result:
+				case <-ctx.Done():
+				}
+			}(target)
+		}
+		wg.Wait()
+	}()
+	return results
+}
+
+// Create a pinger from the options, filling in defaults
+func (o Options) newPinger(ctx context.Context) *Pinger {
+	rate := o.Rate
+	if rate == 0 {
+		rate = DefaultRate
+	} else if rate < 0 {
+		rate = 0 // The limiter treats 0 as no cap
+	}
+	signature := o.Payload
+	if signature == "" {
+		signature = Signature
+	}
+	payloadSize := len(signature)
+	if o.PayloadSize > 0 {
+		payloadSize = o.PayloadSize
+	}
+
+	p := NewPinger(NewLimiter(rate, o.Bandwidth), signature, min(payloadSize, MaxPayloadSize), o.Source)
+	p.Limiter.SetJitter(o.Jitter)
+	p.Context = ctx
+	if o.Datagram {
+		p.UseDatagramSockets()
+	}
+	p.SetTTL(o.TTL)
+	if o.Timeout > 0 {
+		p.Timeout = o.Timeout
+	}
+	if o.Retries > 0 {
+		p.Retries = o.Retries
+	}
+	p.Backoff = o.Backoff
+	return p
+}
+
+// Wrap OnResult so concurrent workers call it one result at a time; a no-op if it is not set
+func (o Options) resultHook() func(Result) {
+	if o.OnResult == nil {
+		return func(Result) {}
+	}
+	var mu sync.Mutex
+	return func(result Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		o.OnResult(result)
+	}
+}
+
+// Resolve and probe one target
+func (p *Pinger) ping(target string) Result {
+	result := Result{Target: target}
+	ip, err := p.resolve(target)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.IP = ip.String()
+
+	// Open the socket up front so missing privileges are reported instead of looking like a dead host
+	if _, err := p.sockets.get(familyOf(ip)); err != nil {
+		result.Err = fmt.Errorf("cannot open an ICMP socket: %w", err)
+		return result
+	}
+	reply, err := p.ProbeWithRetries(result.IP)
+	result.Alive, result.Attempt, result.RTT, result.TTL = err == nil, reply.Attempt, reply.RTT, reply.TTL
+	var permanent *PermanentError
+	if errors.As(err, &permanent) {
+		result.Err = err
+	} else if !result.Alive && p.Context.Err() != nil {
+		result.Err = p.Context.Err()
+	}
+	return result
+}
+
+// Resolve a target to an address, preferring IPv4 like the CLI does
+func (p *Pinger) resolve(target string) (net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(p.Context, target)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", target)
+	}
+	return addrs[0].IP, nil
+}
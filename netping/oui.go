@@ -0,0 +1,44 @@
+package netping
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+)
+
+// IEEE MA-L registry: one "AABBCC<tab>Organization" line per 24-bit OUI
+//
+//go:embed oui.txt
+var ouiTable string
+
+// OUI prefixes mapped to vendor names, parsed on first use
+var (
+	ouiOnce    sync.Once
+	ouiVendors map[[3]byte]string
+)
+
+// Look up the vendor that registered the OUI of a MAC address ("" if unknown or locally administered)
+func Vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 || mac[0]&0x02 != 0 {
+		return "" // Locally administered addresses (randomized or VM-assigned) carry no OUI
+	}
+	ouiOnce.Do(loadOUITable)
+	return ouiVendors[[3]byte(mac[:3])]
+}
+
+// Parse the embedded OUI table
+func loadOUITable() {
+	ouiVendors = make(map[[3]byte]string, strings.Count(ouiTable, "\n"))
+	scanner := bufio.NewScanner(strings.NewReader(ouiTable))
+	for scanner.Scan() {
+		prefix, vendor, ok := strings.Cut(scanner.Text(), "\t")
+		var oui [3]byte
+		if n, err := hex.Decode(oui[:], []byte(prefix)); !ok || err != nil || n != 3 {
+			continue
+		}
+		ouiVendors[oui] = vendor
+	}
+}
@@ -0,0 +1,264 @@
+package netping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// Sends ICMP echo probes with a fixed payload from an optional source address.
+// Set the exported fields before the first probe; a Pinger is safe for concurrent use after that.
+type Pinger struct {
+	Limiter *Limiter
+	Timeout time.Duration   // How long to wait for each reply
+	Retries int             // Attempts per host before giving up
+	Breaker *Breaker        // Pauses sending when sends fail en masse (nil = disabled)
+	Budget  *RetryBudget    // Shrinks retries as a deadline approaches (nil = always use every retry)
+	Backoff Backoff         // Wait between attempts (BackoffFixed = half a timeout)
+	Context context.Context // Cancelling it stops retries and abandons outstanding probes
+	Log     *log.Logger     // Where per-host socket and send errors are reported
+	payload []byte
+	marker  string      // Signature at the start of every payload, kept for the sized -pmtu probes
+	source  string      // Local address to send from ("" = any)
+	sockets *socketPool // Shared ICMP sockets of every probe sent by this pinger and its copies
+}
+
+// Create a pinger whose echo requests carry a payload of the given size, starting with the signature
+func NewPinger(limiter *Limiter, signature string, payloadSize int, source string) *Pinger {
+	return &Pinger{
+		Limiter: limiter,
+		Timeout: DefaultTimeout,
+		Retries: DefaultRetries,
+		Context: context.Background(),
+		Log:     log.Default(),
+		payload: buildPayload(signature, payloadSize),
+		marker:  signature,
+		source:  source,
+		sockets: &socketPool{source: source},
+	}
+}
+
+// Wait for the given duration; returns false early if the context is cancelled
+func (p *Pinger) sleep(d time.Duration) bool {
+	return sleepContext(p.Context, d)
+}
+
+// Wait for the given duration; returns false early if ctx is cancelled
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Close the shared ICMP sockets
+func (p *Pinger) Close() {
+	p.sockets.close()
+}
+
+// Send echo requests from unprivileged datagram ICMP sockets instead of raw ones; on Linux the
+// user's group must be allowed by net.ipv4.ping_group_range. Raw-only probes (Sweep, fragments, path MTU) are unaffected.
+func (p *Pinger) UseDatagramSockets() {
+	p.sockets.datagram = true
+}
+
+// Set the TTL (IPv6 hop limit) of outgoing echo requests, 1-255; 0 keeps the OS default. Probes whose TTL
+// runs out before the target fail, without retries, as soon as a router reports it. Call before the first probe.
+func (p *Pinger) SetTTL(ttl int) {
+	p.sockets.ttl = ttl
+}
+
+// Open the shared socket for the target's IP version, reporting whether it is an unprivileged datagram socket
+func (p *Pinger) OpenSocket(target net.IP) (bool, error) {
+	mux, err := p.sockets.get(familyOf(target))
+	if err != nil {
+		return false, err
+	}
+	return mux.datagram, nil
+}
+
+// Wait until a packet of the given size may be sent; false if the context was cancelled, and the packet must not go out
+func (p *Pinger) pace(packetSize int) bool {
+	return p.Breaker.wait(p.Context) && p.Limiter.Wait(p.Context, packetSize) && p.Context.Err() == nil
+}
+
+// Build an echo payload: the signature, truncated or padded with deterministic bytes to the size
+func buildPayload(signature string, size int) []byte {
+	payload := make([]byte, size)
+	n := copy(payload, signature)
+	for i := n; i < size; i++ {
+		payload[i] = byte(i)
+	}
+	return payload
+}
+
+// Echo reply that showed a host is alive
+type Reply struct {
+	Attempt int // 1-based attempt that got the reply
+	RTT     time.Duration
+	TTL     int // TTL (IPv6 hop limit) the reply arrived with (0 = unknown)
+}
+
+// Failure of a probe that retrying cannot fix, such as an invalid target, a socket that cannot be
+// opened, or a send the OS rejects outright; Probe and ProbeWithRetries return it without retrying
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Returned by Probe and ProbeWithRetries when no echo reply came back, either because the timeout ran out
+// or because a router answered with an ICMP destination unreachable or time exceeded error
+var ErrNoReply = errors.New("no echo reply")
+
+// Probe a host with retries, returning the first reply. Only attempts that time out are retried: an ICMP
+// destination unreachable or time exceeded error returns ErrNoReply at once, since the next attempt would
+// meet the same router, and a *PermanentError is returned as is. ErrNoReply also follows the last timeout.
+func (p *Pinger) ProbeWithRetries(target string) (Reply, error) {
+	retries := p.Budget.attempts(p.Retries, p.Timeout, p.retryDelay(0))
+	for i := 0; i < retries; i++ {
+		status, reply, err := p.probe(target)
+		if status == probeReply {
+			reply.Attempt = i + 1
+			return reply, nil
+		}
+		if status == probeFailed {
+			return Reply{}, err
+		}
+		if status == probeUnreachable || status == probeExpired {
+			return Reply{}, ErrNoReply
+		}
+		if i < retries-1 && !p.sleep(p.retryDelay(i)) { // Wait before retrying, but not after the last attempt
+			break
+		}
+	}
+	return Reply{}, ErrNoReply
+}
+
+// Check if a host is alive with retries, returning the first reply
+func (p *Pinger) IsHostAliveWithRetries(target string) (Reply, bool) {
+	reply, err := p.ProbeWithRetries(target)
+	return reply, err == nil
+}
+
+// Outcome of a single echo request
+type probeStatus int
+
+const (
+	probeTimeout     probeStatus = iota // No matching reply before the deadline
+	probeReply                          // Echo reply from the target
+	probeUnreachable                    // Destination unreachable reported for the target
+	probeExpired                        // TTL ran out on the way to the target (see SetTTL)
+	probeFailed                         // Probe could not be sent and retrying would not help
+)
+
+// Send one ICMP echo request, returning the reply, ErrNoReply, or a *PermanentError
+func (p *Pinger) Probe(target string) (Reply, error) {
+	status, reply, err := p.probe(target)
+	switch status {
+	case probeReply:
+		reply.Attempt = 1
+		return reply, nil
+	case probeFailed:
+		return Reply{}, err
+	}
+	return Reply{}, ErrNoReply
+}
+
+// Check if a host is alive using ICMP echo request
+func (p *Pinger) IsHostAlive(target string) bool {
+	_, err := p.Probe(target)
+	return err == nil
+}
+
+// Log a probe failure that retrying cannot fix and wrap it as a *PermanentError
+func (p *Pinger) permanent(format string, args ...any) (probeStatus, Reply, error) {
+	err := fmt.Errorf(format, args...)
+	p.Log.Printf("Error: %v\n", err)
+	return probeFailed, Reply{}, &PermanentError{Err: err}
+}
+
+// Check whether the OS rejected a send in a way that will not change on retry, unlike a full
+// send buffer or a network that is down for now, which the breaker waits out
+func permanentSendError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EINVAL, syscall.EAFNOSUPPORT, syscall.EADDRNOTAVAIL, syscall.EACCES, syscall.EPERM, syscall.EMSGSIZE} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send one ICMP echo request on the shared socket and wait for the matching reply or error, returning its
+// RTT and TTL; failures that retrying cannot fix are returned as probeFailed with a *PermanentError
+func (p *Pinger) probe(target string) (probeStatus, Reply, error) {
+	targetIP := net.ParseIP(target)
+	if targetIP == nil {
+		return p.permanent("invalid target IP: %s", target)
+	}
+	if p.Context.Err() != nil {
+		return probeTimeout, Reply{}, nil
+	}
+	mux, err := p.sockets.get(familyOf(targetIP))
+	if err != nil {
+		return p.permanent("cannot open an ICMP socket: %w", err)
+	}
+	outcome := make(chan probeOutcome, 1)
+	seq, err := mux.register(targetIP, p.payload, func(result probeOutcome) { outcome <- result })
+	if err != nil {
+		p.Log.Printf("Error sending ICMP request to %s: %v\n", target, err)
+		return probeTimeout, Reply{}, nil
+	}
+	defer mux.unregister(seq)
+
+	// Create ICMP echo request
+	msg := icmp.Message{
+		Type: mux.family.echoRequest, Code: 0,
+		Body: &icmp.Echo{
+			ID: mux.id, Seq: seq,
+			Data: p.payload,
+		},
+	}
+	msgBytes, err := msg.Marshal(nil)
+	if err != nil {
+		return p.permanent("cannot marshal ICMP message: %w", err)
+	}
+
+	// Send ICMP request
+	if !p.pace(mux.family.headerLen + len(msgBytes)) { // Account for IP header + ICMP message
+		return probeTimeout, Reply{}, nil
+	}
+	sent := time.Now()
+	_, err = mux.conn.WriteTo(msgBytes, mux.addr(targetIP))
+	p.Breaker.record(err != nil)
+	if err != nil && permanentSendError(err) {
+		return p.permanent("cannot send ICMP request to %s: %w", target, err)
+	}
+	if err != nil {
+		p.Log.Printf("Error sending ICMP request to %s: %v\n", target, err)
+		return probeTimeout, Reply{}, nil
+	}
+
+	// Wait for the socket's reader to match a reply or error to this request
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+	select {
+	case result := <-outcome:
+		return result.status, Reply{RTT: result.at.Sub(sent), TTL: result.ttl}, nil
+	case <-timer.C:
+		return probeTimeout, Reply{}, nil
+	case <-p.Context.Done():
+		return probeTimeout, Reply{}, nil
+	}
+}
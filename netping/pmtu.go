@@ -0,0 +1,123 @@
+package netping
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// Default upper bound of the path MTU search when the interface MTU is unknown
+const DefaultMTU = 1500
+
+// Find the largest packet that reaches the target with the Don't Fragment bit set, by binary
+// search over payload sizes. Returns 0 if not even the smallest probe got a reply.
+// Like fragmented probes, this writes its own IP headers and needs a raw IP socket.
+func (p *Pinger) DiscoverPathMTU(target string, maxMTU, maxProbes int) int {
+	targetIP := net.ParseIP(target).To4()
+	if targetIP == nil {
+		p.Log.Printf("Invalid target IP: %s\n", target)
+		return 0
+	}
+
+	conn, err := net.ListenPacket("ip4:icmp", p.source)
+	if err != nil {
+		p.Log.Printf("Error creating ICMP connection: %v\n", err)
+		return 0
+	}
+	defer conn.Close()
+	rawConn, err := ipv4.NewRawConn(conn)
+	if err != nil {
+		p.Log.Printf("Error creating raw IP connection: %v\n", err)
+		return 0
+	}
+
+	// Search payload sizes; the smallest probe must fit or the host is not reachable with DF at all
+	lo, hi := 0, maxMTU-ipv4.HeaderLen-ICMPHeaderLen
+	if fits, _ := p.probeDF(rawConn, targetIP, lo, 1); !fits {
+		return 0
+	}
+	for probe := 2; lo < hi && probe <= maxProbes; probe++ {
+		size := (lo + hi + 1) / 2
+		fits, nextHopMTU := p.probeDF(rawConn, targetIP, size, probe)
+		if fits {
+			lo = size
+			continue
+		}
+		hi = size - 1
+		// Routers report the next-hop MTU in "fragmentation needed" errors; use it to narrow the search
+		if limit := nextHopMTU - ipv4.HeaderLen - ICMPHeaderLen; nextHopMTU > 0 && limit < hi && limit >= lo {
+			hi = limit
+		}
+	}
+	return lo + ipv4.HeaderLen + ICMPHeaderLen
+}
+
+// Send one echo request of the given payload size with DF set; reports whether the reply came
+// back and, for "fragmentation needed" errors, the next-hop MTU the router advertised
+func (p *Pinger) probeDF(rawConn *ipv4.RawConn, target net.IP, size, seq int) (bool, int) {
+	id := os.Getpid() & 0xffff
+	data := buildPayload(p.marker, size)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: data},
+	}
+	msgBytes, err := msg.Marshal(nil)
+	if err != nil {
+		p.Log.Printf("Error marshaling ICMP message: %v\n", err)
+		return false, 0
+	}
+	header := &ipv4.Header{
+		Version:  ipv4.Version,
+		Len:      ipv4.HeaderLen,
+		TotalLen: ipv4.HeaderLen + len(msgBytes),
+		Flags:    ipv4.DontFragment,
+		TTL:      64,
+		Protocol: 1, // ICMP
+		Dst:      target,
+	}
+
+	if !p.pace(header.TotalLen) {
+		return false, 0
+	}
+	sent := time.Now()
+	if err := rawConn.WriteTo(header, msgBytes, nil); err != nil {
+		// The local interface MTU is already too small for this probe
+		if errors.Is(err, syscall.EMSGSIZE) {
+			return false, 0
+		}
+		p.Breaker.record(true)
+		p.Log.Printf("Error sending ICMP request to %s: %v\n", target, err)
+		return false, 0
+	}
+
+	p.Breaker.record(false)
+	rawConn.SetReadDeadline(sent.Add(p.Timeout))
+	buffer := make([]byte, 65536)
+	for {
+		replyHeader, payload, _, err := rawConn.ReadFrom(buffer)
+		if err != nil {
+			return false, 0 // No answer: treat the size as blackholed
+		}
+		reply, err := icmp.ParseMessage(ipv4.ICMPTypeEchoReply.Protocol(), payload)
+		if err != nil {
+			continue
+		}
+		switch body := reply.Body.(type) {
+		case *icmp.Echo:
+			if reply.Type == ipv4.ICMPTypeEchoReply && replyHeader.Src.Equal(target) && body.ID == id && body.Seq == seq && bytes.Equal(body.Data, data) {
+				return true, 0
+			}
+		case *icmp.DstUnreach:
+			// Code 4 is "fragmentation needed and DF set"; bytes 6-7 carry the next-hop MTU
+			if reply.Code == 4 && icmpv4.quotesEcho(body.Data, target, id) && len(payload) >= 8 {
+				return false, int(payload[6])<<8 | int(payload[7])
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package netping
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Packet rates that apply during time-of-day ranges
+type RateProfile struct {
+	entries []profileEntry
+}
+
+// A time-of-day range, in minutes since midnight, and its rate
+type profileEntry struct {
+	start, end int // Ranges with end <= start wrap around midnight
+	rate       int
+}
+
+// Read a rate profile: one "HH:MM-HH:MM RATE" line per range, '#' starts a comment
+func LoadRateProfile(path string) (*RateProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profile := &RateProfile{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entry, err := parseProfileEntry(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		profile.entries = append(profile.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(profile.entries) == 0 {
+		return nil, fmt.Errorf("no time ranges defined")
+	}
+	return profile, nil
+}
+
+// Parse the fields of one profile line
+func parseProfileEntry(fields []string) (profileEntry, error) {
+	if len(fields) != 2 {
+		return profileEntry{}, fmt.Errorf("expected 'HH:MM-HH:MM RATE'")
+	}
+	from, to, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return profileEntry{}, fmt.Errorf("invalid time range '%s'", fields[0])
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return profileEntry{}, err
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return profileEntry{}, err
+	}
+	rate, err := strconv.Atoi(fields[1])
+	if err != nil || rate < 0 {
+		return profileEntry{}, fmt.Errorf("invalid rate '%s'", fields[1])
+	}
+	return profileEntry{start: start, end: end, rate: rate}, nil
+}
+
+// Parse "HH:MM" into minutes since midnight; "24:00" marks the end of the day
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", value)
+}
+
+// Format a packet rate for progress output
+func FormatRate(rate int) string {
+	if rate == 0 {
+		return "unlimited rate"
+	}
+	return fmt.Sprintf("%d probes/s", rate)
+}
+
+// Rate of the first range containing the given time, if any
+func (p *RateProfile) rateAt(t time.Time) (int, bool) {
+	minute := t.Hour()*60 + t.Minute()
+	for _, entry := range p.entries {
+		if entry.start < entry.end && minute >= entry.start && minute < entry.end {
+			return entry.rate, true
+		}
+		if entry.start >= entry.end && (minute >= entry.start || minute < entry.end) {
+			return entry.rate, true
+		}
+	}
+	return 0, false
+}
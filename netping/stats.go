@@ -0,0 +1,50 @@
+package netping
+
+import "time"
+
+// Packet loss and round-trip times of a series of echo requests, like the summary of ping -c
+type Stats struct {
+	Sent     int
+	Received int
+	MinRTT   time.Duration
+	AvgRTT   time.Duration
+	MaxRTT   time.Duration
+}
+
+// Percentage of the echo requests that got no reply
+func (s Stats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+}
+
+// Send count echo requests to a host and gather loss and RTT statistics, returning them
+// with the first reply (Attempt 0 if none came back); stops early if the pinger's context is cancelled
+// or a request fails in a way retrying cannot fix
+func (p *Pinger) PingStats(target string, count int) (Stats, Reply) {
+	var stats Stats
+	var first Reply
+	var total time.Duration
+	for i := 0; i < count && p.Context.Err() == nil; i++ {
+		status, reply, _ := p.probe(target)
+		if status == probeFailed {
+			break // Nothing was sent, and later requests would fail the same way
+		}
+		stats.Sent++
+		if status != probeReply {
+			continue
+		}
+		stats.Received++
+		total += reply.RTT
+		if first.Attempt == 0 {
+			first, first.Attempt = reply, i+1
+			stats.MinRTT, stats.MaxRTT = reply.RTT, reply.RTT
+		}
+		stats.MinRTT, stats.MaxRTT = min(stats.MinRTT, reply.RTT), max(stats.MaxRTT, reply.RTT)
+	}
+	if stats.Received > 0 {
+		stats.AvgRTT = total / time.Duration(stats.Received)
+	}
+	return stats, first
+}
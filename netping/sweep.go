@@ -0,0 +1,154 @@
+package netping
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// Spread the targets across several sockets, each sweeping its share with its own echo ID
+// and reply map; returns the first reply of each host
+func (p *Pinger) Sweep(targets []string, window time.Duration, sockets int) map[string]Reply {
+	// IPv4 and IPv6 targets each get their own set of sockets
+	shards := make([][]string, 2*sockets)
+	var v4, v6 int
+	for _, target := range targets {
+		if ip := net.ParseIP(target); ip != nil && ip.To4() == nil {
+			shards[sockets+v6%sockets] = append(shards[sockets+v6%sockets], target)
+			v6++
+		} else {
+			shards[v4%sockets] = append(shards[v4%sockets], target)
+			v4++
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	replied := map[string]Reply{}
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		family := icmpv4
+		if i >= sockets {
+			family = icmpv6
+		}
+		wg.Add(1)
+		go func(id int, shard []string) {
+			defer wg.Done()
+			shardReplies := p.sweepSocket(shard, window, id, family)
+			mu.Lock()
+			for host, reply := range shardReplies {
+				replied[host] = reply
+			}
+			mu.Unlock()
+		}((os.Getpid()+i%sockets)&0xffff, shard)
+	}
+	wg.Wait()
+	return replied
+}
+
+// Send rounds of echo requests to every host that has not replied yet, keeping one reader
+// running until the collect window after the last send; returns the first reply of each host
+func (p *Pinger) sweepSocket(targets []string, window time.Duration, id int, family icmpFamily) map[string]Reply {
+	conn, err := icmp.ListenPacket(family.network, family.bindAddress(p.source))
+	if err != nil {
+		p.Log.Printf("Error creating ICMP connection: %v\n", err)
+		return nil
+	}
+	family.enableTTL(conn)
+	if err := family.setTTL(conn, p.sockets.ttl); err != nil {
+		p.Log.Printf("Error setting TTL %d: %v\n", p.sockets.ttl, err)
+	}
+
+	var mu sync.Mutex
+	sentAt := map[string][]time.Time{} // When each round's probe to each host was sent, indexed by round-1
+	replied := map[string]Reply{}      // First reply of each host
+
+	// Collect replies until the socket is closed
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		buffer := make([]byte, 1500+len(p.payload))
+		for {
+			n, peer, ttl, err := family.readFrom(conn, buffer)
+			if err != nil {
+				return
+			}
+			msg, err := icmp.ParseMessage(family.protocol, buffer[:n])
+			if err != nil || msg.Type != family.echoReply {
+				continue
+			}
+			echo, ok := msg.Body.(*icmp.Echo)
+			peerIP, isIP := peer.(*net.IPAddr)
+			if !ok || !isIP || echo.ID != id || !bytes.Equal(echo.Data, p.payload) {
+				continue
+			}
+			// The sequence number is the round; a late reply to an earlier round is timed from that round's send
+			host := peerIP.IP.String()
+			mu.Lock()
+			if _, done := replied[host]; !done {
+				if sends := sentAt[host]; echo.Seq >= 1 && echo.Seq <= len(sends) {
+					replied[host] = Reply{Attempt: echo.Seq, RTT: time.Since(sends[echo.Seq-1]), TTL: ttl}
+				}
+			}
+			mu.Unlock()
+		}
+	}()
+
+	for round := 1; round <= p.Retries && p.Context.Err() == nil; round++ {
+		for _, target := range targets {
+			if p.Context.Err() != nil {
+				break
+			}
+			mu.Lock()
+			_, done := replied[target]
+			mu.Unlock()
+			if done {
+				continue
+			}
+
+			targetIP := net.ParseIP(target)
+			if targetIP == nil {
+				p.Log.Printf("Invalid target IP: %s\n", target)
+				continue
+			}
+			msg := icmp.Message{
+				Type: family.echoRequest, Code: 0,
+				Body: &icmp.Echo{ID: id, Seq: round, Data: p.payload},
+			}
+			msgBytes, err := msg.Marshal(nil)
+			if err != nil {
+				p.Log.Printf("Error marshaling ICMP message: %v\n", err)
+				continue
+			}
+			if !p.pace(family.headerLen + len(msgBytes)) { // Account for IP header + ICMP message
+				break
+			}
+			mu.Lock()
+			sentAt[target] = append(sentAt[target], time.Now())
+			mu.Unlock()
+			_, err = conn.WriteTo(msgBytes, &net.IPAddr{IP: targetIP})
+			p.Breaker.record(err != nil)
+			if err != nil {
+				p.Log.Printf("Error sending ICMP request to %s: %v\n", target, err)
+			}
+		}
+		if round < p.Retries {
+			p.sleep(p.retryDelay(round - 1)) // Wait before retrying
+		}
+	}
+
+	// Keep collecting stragglers for the grace window after the last send
+	p.sleep(window)
+	conn.Close()
+	<-readerDone
+
+	mu.Lock()
+	defer mu.Unlock()
+	return replied
+}
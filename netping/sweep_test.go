@@ -0,0 +1,36 @@
+package netping
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+)
+
+// Sweep the loopback /24 with 1 to 8 sockets, to show how the socket count changes scan time
+func BenchmarkSweepSockets(b *testing.B) {
+	conn, err := icmp.ListenPacket(icmpv4.network, "")
+	if err != nil {
+		b.Skipf("raw ICMP sockets unavailable (run as root): %v", err)
+	}
+	conn.Close()
+
+	targets := make([]string, 256)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("127.0.0.%d", i)
+	}
+	for _, sockets := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("sockets=%d", sockets), func(b *testing.B) {
+			p := NewPinger(NewLimiter(0, 0), Signature, len(Signature), "")
+			p.Retries, p.Timeout, p.Log = 1, time.Second, log.New(io.Discard, "", 0)
+			var replies int
+			for i := 0; i < b.N; i++ {
+				replies += len(p.Sweep(targets, 50*time.Millisecond, sockets))
+			}
+			b.ReportMetric(float64(replies)/float64(b.N), "replies/op") // Replies lost to full socket buffers show up here
+		})
+	}
+}
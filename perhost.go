@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const perHostFileLimit = 16 // Result files created at once with -per-host-dir
+
+// Writes each alive host's result to its own JSON file
+type perHostWriter struct {
+	dir string
+	sem chan struct{} // Bounds open files so large scans do not exhaust descriptors
+}
+
+// Create the per-host output directory if needed
+func newPerHostWriter(dir string) (*perHostWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &perHostWriter{dir: dir, sem: make(chan struct{}, perHostFileLimit)}, nil
+}
+
+// Write a result to <dir>/<ip>.json, renaming a temp file into place so watchers never see a partial file
+func (w *perHostWriter) write(result Result) error {
+	w.sem <- struct{}{}
+	defer func() { <-w.sem }()
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Colons are not allowed in Windows file names, so IPv6 addresses use dashes
+	name := strings.ReplaceAll(result.IP, ":", "-") + ".json"
+	tmp, err := os.CreateTemp(w.dir, ".netping-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(w.dir, name))
+}
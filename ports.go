@@ -0,0 +1,12 @@
+package main
+
+import "slices"
+
+// Check the -ports of an alive host, adding the open ones to any found by its probe; runs in the caller's semaphore slot
+func (s *scanState) scanPorts(result *Result) {
+	if s.portScan == nil || !result.Alive || result.IP == "" {
+		return
+	}
+	open, _, _ := s.portScan.scan(result.IP)
+	result.OpenPorts = slices.Compact(slices.Sorted(slices.Values(append(result.OpenPorts, open...))))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pinger/netping"
+)
+
+// Weight of the newest sample in the completion-rate moving average; lower values swing less
+const etaSmoothing = 0.2
+
+// Estimates the time left in a scan from a moving average of its completion rate
+type etaEstimator struct {
+	lastAt    time.Time
+	lastCount int32
+	rate      float64 // Hosts completed per second, smoothed
+}
+
+// Record the number of hosts completed so far
+func (e *etaEstimator) observe(count int32, now time.Time) {
+	if e.lastAt.IsZero() {
+		e.lastAt, e.lastCount = now, count
+		return
+	}
+	elapsed := now.Sub(e.lastAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	sample := float64(count-e.lastCount) / elapsed
+	if e.rate == 0 {
+		e.rate = sample // Nothing completes until the first replies or timeouts; start from the first real sample
+	} else {
+		e.rate = etaSmoothing*sample + (1-etaSmoothing)*e.rate
+	}
+	e.lastAt, e.lastCount = now, count
+}
+
+// Estimated time to complete the remaining hosts; false until the rate is known
+func (e *etaEstimator) remaining(left int32) (time.Duration, bool) {
+	if e.rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(left) / e.rate * float64(time.Second)), true
+}
+
+// Format the progress line: count, percentage, effective rate if shown, and ETA (nil eta = final line)
+func formatProgress(progress, total int32, rate int, showRate bool, eta *etaEstimator) string {
+	line := fmt.Sprintf("Pinging: %d/%d hosts", progress, total)
+	if total > 0 {
+		line += fmt.Sprintf(" (%.1f%%)", float64(progress)*100/float64(total))
+	}
+	if showRate {
+		line += " at " + netping.FormatRate(rate)
+	}
+	if eta != nil && progress < total {
+		if left, ok := eta.remaining(total - progress); ok {
+			line += " ETA " + left.Round(time.Second).String()
+		}
+	}
+	return line + "   " // Pad over the tail of a longer previous line
+}
+
+// Progress event written as one JSON line with -progress-json
+type progressEvent struct {
+	Done  int32 `json:"done"`
+	Total int32 `json:"total"`
+	Alive int32 `json:"alive"`
+}
+
+// Open the -progress-json destination: standard error for "-", or a file such as /dev/fd/3,
+// appended to so the scans of a watch form one stream
+func openProgressStream(dest string) (*os.File, error) {
+	if dest == "-" {
+		return os.Stderr, nil
+	}
+	return os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// Look up the reverse-DNS name of an alive host with -resolve-ptr; hosts without a PTR record keep no name
+func (s *scanState) lookupPTR(result *Result) {
+	if !s.opts.resolvePTR || !result.Alive {
+		return
+	}
+	names, err := net.LookupAddr(result.IP)
+	if err != nil || len(names) == 0 {
+		return
+	}
+	result.PTR = strings.TrimSuffix(names[0], ".")
+}
+
+// Look up the reverse-DNS names and check the -ports of alive results concurrently, each host holding a semaphore slot
+func (s *scanState) followUpAlive(results []Result) {
+	if !s.opts.resolvePTR && s.portScan == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for i := range results {
+		if !results[i].Alive {
+			continue
+		}
+		if !s.acquire() {
+			break
+		}
+		wg.Add(1)
+		go func(result *Result) {
+			defer wg.Done()
+			defer func() { <-s.sem }() // Release the semaphore slot
+			s.lookupPTR(result)
+			s.scanPorts(result)
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// Format a host for the text output; the PTR name goes in a comment so the file is still a valid target file
+func textLine(result Result) string {
+	if result.PTR == "" {
+		return result.IP
+	}
+	return result.IP + " # " + result.PTR
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// One entry of a -record file: a host result, an unresolved domain, or an invalid target line. A -resume
+// checkpoint also marks each resolved domain once every one of its addresses has a result
+type recordedEvent struct {
+	Result     *Result `json:"result,omitempty"`
+	Unresolved string  `json:"unresolved,omitempty"`
+	Invalid    string  `json:"invalid,omitempty"`
+	Resolved   string  `json:"resolved,omitempty"`
+}
+
+// Writes every event of a scan to a JSON lines file as it happens
+type scanRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// Create the record file
+func newScanRecorder(path string) (*scanRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &scanRecorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Append an event to the record file
+func (r *scanRecorder) add(event recordedEvent) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encoder.Encode(event)
+}
+
+// Close the record file
+func (r *scanRecorder) close() error {
+	if r == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// Read the events of a -record file
+func readRecordedEvents(path string) ([]recordedEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []recordedEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var event recordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: invalid NetPing record: %v", lineNumber, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Feed recorded events through the output pipeline instead of probing, restoring the
+// counters that are normally updated while probing
+func (s *scanState) replay(events []recordedEvent) {
+	s.replaying = true
+	defer func() { s.replaying = false }()
+	for _, event := range events {
+		switch {
+		case event.Invalid != "":
+			s.handleInvalid(event.Invalid)
+		case event.Unresolved != "":
+			s.handleUnresolved(event.Unresolved)
+		case event.Result != nil:
+			result := *event.Result
+			if result.Tier != "" {
+				s.tierCounts[result.Tier]++
+			}
+			if result.FragmentOK != nil && !*result.FragmentOK {
+				atomic.AddInt32(&s.fragFailed, 1)
+				if s.opts.verbose {
+					fmt.Printf("Host %s did not reply to fragmented probes\n", result.IP)
+				}
+			}
+			if result.PathMTU > 0 && s.opts.verbose {
+				fmt.Printf("Host %s path MTU %d\n", result.IP, result.PathMTU)
+			}
+			if result.Unconfirmed {
+				atomic.AddInt32(&s.unconfirmedCount, 1)
+			}
+			if result.Recounted {
+				if result.Alive {
+					atomic.AddInt32(&s.recountAlive, 1)
+				} else {
+					atomic.AddInt32(&s.recountDead, 1)
+				}
+				if s.opts.verbose {
+					fmt.Printf("Host %s re-probed: alive=%t\n", result.IP, result.Alive)
+				}
+			}
+			s.handleResult(result)
+		}
+	}
+}
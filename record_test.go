@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	// IP:PORT lines go through the stubbed dialer, so the scan needs no network
+	dialTCP = func(ip string, port int, timeout time.Duration) (bool, bool) {
+		return ip == "10.0.0.5", true
+	}
+	defer func() { dialTCP = dialPort }()
+
+	dir := t.TempDir()
+	targets := filepath.Join(dir, "targets.txt")
+	if err := os.WriteFile(targets, []byte("10.0.0.5:443\n10.0.0.6:22\n10.0.0.300\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	record := filepath.Join(dir, "scan.jsonl")
+
+	// InfluxDB records are stamped with the time they are written, so only the other formats can match exactly
+	for _, format := range []string{"text", "json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			scanned, replayed := filepath.Join(dir, "scanned."+format), filepath.Join(dir, "replayed."+format)
+			scan := func(output string, args ...string) {
+				fs := newFlagSet("scan")
+				opts := addScanFlags(fs)
+				parseScanFlags(fs, opts, append(args, "-output-format", format, "-output-file", output, "-scanner-id", "test", "-retries", "1"))
+				runScan(opts)
+			}
+			scan(scanned, "-target-file", targets, "-record", record)
+			scan(replayed, "-replay", record)
+
+			want, err := os.ReadFile(scanned)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(replayed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(want) == 0 || string(got) != string(want) {
+				t.Errorf("replayed output =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
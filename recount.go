@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"pinger/netping"
+)
+
+// Check whether a host's state is uncertain: it only replied after retries, or its replies were intermittent
+func isAmbiguous(result Result) bool {
+	return result.Alive && (result.Attempt > 1 || result.Tier == netping.TierIntermittent)
+}
+
+// Hold back ambiguous results for the -recount pass, and handle the rest right away
+func (s *scanState) finishResult(result Result) {
+	if s.opts.recount && isAmbiguous(result) {
+		s.mu.Lock()
+		s.ambiguous = append(s.ambiguous, result)
+		s.mu.Unlock()
+		return
+	}
+	s.handleResult(result)
+}
+
+// Re-probe the held-back ambiguous hosts with more retries and a longer timeout, then handle their final results
+func (s *scanState) recountAmbiguous() {
+	s.mu.Lock()
+	ambiguous := s.ambiguous
+	s.ambiguous = nil
+	s.mu.Unlock()
+	if len(ambiguous) == 0 {
+		return
+	}
+
+	recounter := *s.pinger
+	recounter.Retries *= 2
+	recounter.Timeout *= 2
+
+	var wg sync.WaitGroup
+	for _, result := range ambiguous {
+		// Keep the first result of hosts the interrupted scan can no longer re-probe
+		if !s.acquire() {
+			s.handleResult(result)
+			continue
+		}
+		wg.Add(1)
+		go func(result Result) {
+			defer wg.Done()
+			defer func() { <-s.sem }() // Release the semaphore slot
+
+			reply, alive := recounter.IsHostAliveWithRetries(result.IP)
+			if !alive && s.interrupted() {
+				s.handleResult(result)
+				return
+			}
+			result.Recounted = true
+			if alive {
+				result.Attempt, result.RTTMs = reply.Attempt, rttMs(reply.RTT)
+				result.TTL, result.OSGuess = reply.TTL, guessOS(reply.TTL)
+				atomic.AddInt32(&s.recountAlive, 1)
+			} else {
+				result.Alive, result.Attempt, result.RTTMs, result.TTL, result.OSGuess = false, 0, nil, 0, ""
+				atomic.AddInt32(&s.recountDead, 1)
+			}
+			if s.opts.verbose {
+				fmt.Printf("Host %s re-probed: alive=%t\n", result.IP, alive)
+			}
+			s.handleResult(result)
+		}(result)
+	}
+	wg.Wait()
+}
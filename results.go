@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pinger/netping"
+)
+
+// Result of probing a single host, shared by every structured output format
+type Result struct {
+	IP           string   `json:"ip"`
+	Alive        bool     `json:"alive"`
+	Attempt      int      `json:"attempt,omitempty"`       // 1-based attempt that got a reply
+	RTTMs        *float64 `json:"rtt_ms,omitempty"`        // Round-trip time of the successful attempt
+	ResolvedFrom string   `json:"resolved_from,omitempty"` // Original domain or CIDR range
+	Tier         string   `json:"tier,omitempty"`          // Response tier in -classify mode
+	New          bool     `json:"new,omitempty"`           // Not in the -known-hosts file before this run
+	FragmentOK   *bool    `json:"fragment_ok,omitempty"`   // Replied to fragmented probes (-fragment only)
+	Unconfirmed  bool     `json:"unconfirmed,omitempty"`   // Replied, but the -confirm probe failed
+	PathMTU      int      `json:"path_mtu,omitempty"`      // Largest packet that got through with DF set (-pmtu only)
+	Recounted    bool     `json:"recounted,omitempty"`     // Ambiguous host re-probed by -recount
+	HTTPStatus   int      `json:"http_status,omitempty"`   // Final status code in -probe http mode
+	OpenPorts    []int    `json:"open_ports,omitempty"`    // Open ports in -probe tcp mode, or of alive hosts with -ports
+	Redirects    []string `json:"redirects,omitempty"`     // URLs redirected through before the final response
+	Scanner      string   `json:"scanner,omitempty"`       // -scanner-id of the NetPing instance that probed the host
+	Method       string   `json:"method,omitempty"`        // Probe that decided the host's state with -tcp-ports or -arp: icmp, tcp, or arp
+	PTR          string   `json:"ptr,omitempty"`           // Reverse-DNS name of an alive host (-resolve-ptr only)
+	TTL          int      `json:"ttl,omitempty"`           // TTL (IPv6 hop limit) of the echo reply as received
+	OSGuess      string   `json:"os_guess,omitempty"`      // Coarse OS family implied by the reply TTL
+	Sent         int      `json:"sent,omitempty"`          // Echo requests sent with -ping-count
+	Received     *int     `json:"received,omitempty"`      // Echo replies received with -ping-count
+	LossPct      *float64 `json:"loss_pct,omitempty"`      // Percentage of echo requests that got no reply
+	RTTMinMs     *float64 `json:"rtt_min_ms,omitempty"`    // Fastest round trip of the -ping-count replies
+	RTTAvgMs     *float64 `json:"rtt_avg_ms,omitempty"`    // Mean round trip of the -ping-count replies
+	RTTMaxMs     *float64 `json:"rtt_max_ms,omitempty"`    // Slowest round trip of the -ping-count replies
+	MAC          string   `json:"mac,omitempty"`           // Hardware address from the ARP reply of an on-link host (-arp only)
+	Vendor       string   `json:"vendor,omitempty"`        // Organization that registered the MAC address's OUI prefix
+}
+
+// Probe methods reported with -tcp-ports and -arp
+const (
+	methodICMP = "icmp"
+	methodTCP  = "tcp"
+	methodARP  = "arp"
+)
+
+// Convert a round-trip time to milliseconds for structured output
+func rttMs(rtt time.Duration) *float64 {
+	ms := float64(rtt.Microseconds()) / 1000
+	return &ms
+}
+
+// Fill in the loss and RTT statistics of a -ping-count series
+func (r *Result) setStats(stats netping.Stats) {
+	loss := stats.Loss()
+	r.Sent, r.Received, r.LossPct = stats.Sent, &stats.Received, &loss
+	if stats.Received > 0 {
+		r.RTTMinMs, r.RTTAvgMs, r.RTTMaxMs = rttMs(stats.MinRTT), rttMs(stats.AvgRTT), rttMs(stats.MaxRTT)
+	}
+}
+
+// Guess the OS family from a reply TTL by the initial TTL it most likely started from:
+// 64 for Linux, macOS and other Unix, 128 for Windows, 255 for routers and other network gear
+func guessOS(ttl int) string {
+	switch {
+	case ttl <= 0:
+		return ""
+	case ttl <= 64:
+		return "linux/unix"
+	case ttl <= 128:
+		return "windows"
+	default:
+		return "network device"
+	}
+}
+
+// Writes the results collected during a scan in one output format
+type resultFormatter interface {
+	write(w io.Writer, results []Result) error
+}
+
+// Indented JSON array, optionally grouped by the domain each address was resolved from
+type jsonFormatter struct {
+	groupByDomain bool
+}
+
+func (f jsonFormatter) write(w io.Writer, results []Result) error {
+	if f.groupByDomain {
+		return writeGroupedResults(w, results)
+	}
+	return writeJSONResults(w, results)
+}
+
+// Columns of CSV output; new columns are only ever added at the end
+var csvHeader = []string{"ip", "hostname", "alive", "rtt_ms", "ttl", "method", "open_ports"}
+
+// CSV with a header row and one row per host, offline hosts included
+type csvFormatter struct {
+	probe string // Method of hosts whose result does not name one: -probe
+}
+
+func (f csvFormatter) write(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+	for _, result := range results {
+		rtt, ttl := "", ""
+		if result.RTTMs != nil {
+			rtt = strconv.FormatFloat(*result.RTTMs, 'f', -1, 64)
+		}
+		if result.TTL > 0 {
+			ttl = strconv.Itoa(result.TTL)
+		}
+		method := result.Method
+		if method == "" {
+			method = f.probe
+		}
+		ports := make([]string, len(result.OpenPorts))
+		for i, port := range result.OpenPorts {
+			ports[i] = strconv.Itoa(port)
+		}
+		writer.Write([]string{result.IP, resultHostname(result), strconv.FormatBool(result.Alive), rtt, ttl, method, strings.Join(ports, " ")})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Name of a host in tabular outputs: the domain it was scanned by, or else its reverse-DNS name if looked up
+func resultHostname(result Result) string {
+	if isDomain(result.ResolvedFrom) {
+		return result.ResolvedFrom
+	}
+	return result.PTR
+}
+
+// Alive hosts one per line, for text output held back by -sort
+type textFormatter struct{}
+
+func (textFormatter) write(w io.Writer, results []Result) error {
+	for _, result := range results {
+		if !result.Alive {
+			continue
+		}
+		if _, err := io.WriteString(w, textLine(result)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// One compact JSON object per line, for JSON Lines output held back by -sort
+type jsonlFormatter struct{}
+
+func (jsonlFormatter) write(w io.Writer, results []Result) error {
+	for _, result := range results {
+		if _, err := io.WriteString(w, jsonLine(result)+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pick the formatter of a structured output format; nil for formats written as hosts finish:
+// influx, and text and jsonl unless -sort holds them back (-live already writes text sorted)
+func newResultFormatter(opts *scanOptions) resultFormatter {
+	switch opts.outputFormat {
+	case "json":
+		return jsonFormatter{groupByDomain: opts.groupByDomain}
+	case "csv":
+		return csvFormatter{probe: opts.probe}
+	case "text":
+		if opts.sort && !opts.live {
+			return textFormatter{}
+		}
+	case "jsonl":
+		if opts.sort {
+			return jsonlFormatter{}
+		}
+	}
+	return nil
+}
+
+// Order results numerically by IP for -sort, keeping the order of results for the same host
+func sortResults(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool { return hostLess(results[i].IP, results[j].IP) })
+}
+
+// Format a result as a compact single-line JSON object, for JSON Lines output
+func jsonLine(result Result) string {
+	line, _ := json.Marshal(result) // Result has no types that fail to marshal
+	return string(line)
+}
+
+// Write results as an indented JSON array
+func writeJSONResults(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// Results of every address of one domain target; other targets form a group of one address
+type domainResult struct {
+	Domain         string   `json:"domain,omitempty"`
+	Alive          bool     `json:"alive"`           // At least one address replied
+	AliveAddresses int      `json:"alive_addresses"` // Number of addresses that replied
+	Addresses      []Result `json:"addresses"`
+}
+
+// Write results grouped by the domain they were resolved from, as an indented JSON array
+func writeGroupedResults(w io.Writer, results []Result) error {
+	groups := []domainResult{}
+	byDomain := map[string]int{} // Index of each domain's group
+	for _, result := range results {
+		domain := result.ResolvedFrom
+		if !isDomain(domain) {
+			domain = "" // Hosts of CIDR and START-END ranges are not grouped
+		}
+		index, ok := byDomain[domain]
+		if !ok || domain == "" {
+			index = len(groups)
+			groups = append(groups, domainResult{Domain: domain, Addresses: []Result{}})
+			if domain != "" {
+				byDomain[domain] = index
+			}
+		}
+		// Unresolved domains have a group but no address
+		if result.IP == "" {
+			continue
+		}
+		group := &groups[index]
+		group.Addresses = append(group.Addresses, result)
+		if result.Alive {
+			group.Alive = true
+			group.AliveAddresses++
+		}
+	}
+
+	// Order groups and their addresses so output is stable across runs
+	for _, group := range groups {
+		sort.Slice(group.Addresses, func(i, j int) bool { return hostLess(group.Addresses[i].IP, group.Addresses[j].IP) })
+	}
+	// Domains come first, alphabetically, then ungrouped hosts by address
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Domain != "" || b.Domain != "" {
+			return b.Domain == "" || (a.Domain != "" && a.Domain < b.Domain)
+		}
+		return hostLess(a.Addresses[0].IP, b.Addresses[0].IP)
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(groups)
+}
+
+// Read the results of a previous JSON output
+func readJSONResults(path string) ([]Result, error) {
+	data, err := readTargetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("invalid NetPing JSON output: %v", err)
+	}
+	return results, nil
+}
+
+// Convert previous results into target lines, keeping only alive or dead hosts if requested
+func resultsToTargets(results []Result, filter string) ([]string, error) {
+	if filter != "all" && filter != "alive" && filter != "dead" {
+		return nil, fmt.Errorf("unknown filter '%s' (expected all, alive, or dead)", filter)
+	}
+
+	var targets []string
+	for _, result := range results {
+		if (filter == "alive" && !result.Alive) || (filter == "dead" && result.Alive) {
+			continue
+		}
+		// Unresolved domains have no IP; re-scan them by name
+		if result.IP != "" {
+			targets = append(targets, result.IP)
+		} else if result.ResolvedFrom != "" {
+			targets = append(targets, result.ResolvedFrom)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Hosts that an earlier, interrupted run recorded in a -resume checkpoint
+type checkpoint struct {
+	events []recordedEvent // Results and unresolved domains, replayed so the output covers the earlier run too
+	hosts  *addrSet        // Addresses that already have a result
+	lines  map[string]bool // Domain lines whose every address was probed or that did not resolve, and IP:PORT lines that were checked
+}
+
+// Read a -resume checkpoint; a missing file starts an empty one. The checkpoint is appended to one
+// event at a time, so a crash can only leave the last line cut short, which is dropped.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{hosts: newAddrSet(), lines: map[string]bool{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return nil, err
+		}
+	}
+	events, err := readRecordedEvents(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		switch {
+		case event.Unresolved != "":
+			c.lines[event.Unresolved] = true
+		case event.Resolved != "":
+			c.lines[event.Resolved] = true // Only a marker; the domain's results are events of their own
+			continue
+		case event.Result != nil && isHostPort(event.Result.ResolvedFrom):
+			c.lines[event.Result.ResolvedFrom] = true // A port check says nothing about a plain line for the same address
+		case event.Result != nil:
+			// Ignores the empty IP of a domain that did not resolve. A domain without its marker is resolved
+			// again, and only its addresses missing here are probed
+			c.hosts.add(event.Result.IP, false)
+		default:
+			continue // Invalid lines are reported again as the target files are read
+		}
+		c.events = append(c.events, event)
+	}
+	return c, nil
+}
+
+// Leave out the domain and IP:PORT lines the checkpoint already covers; addresses are skipped as the lines are expanded
+func (c *checkpoint) remaining(lines []string) []string {
+	var kept []string
+	for _, line := range lines {
+		if !c.lines[line] {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// Count the addresses of a target line that already have a result, other than those excluded or covered by an earlier line
+func (c *checkpoint) finishedHosts(line string, skipEdges bool, excluded, seen *addrSet) int32 {
+	if c == nil {
+		return 0
+	}
+	var finished int32
+	for ip := range lineHosts(line, skipEdges) {
+		if c.hosts.contains(ip) && !excluded.contains(ip) && !seen.contains(ip) {
+			finished++
+		}
+	}
+	return finished
+}
+
+// Note how many addresses of a resolved domain are left to probe, marking the domain done in the checkpoint if none are
+func (s *scanState) trackDomain(domain string, addresses int) {
+	if s.domainsLeft == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if addresses == 0 {
+		s.recorder.add(recordedEvent{Resolved: domain})
+		return
+	}
+	s.domainsLeft[domain] += addresses
+}
+
+// Count a finished address of a resolved domain, marking the domain done in the checkpoint after its last one;
+// an interrupted -resolve-all domain stays unmarked, so the next run probes the addresses it missed
+func (s *scanState) domainAddressDone(domain string) {
+	if s.domainsLeft == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	left, ok := s.domainsLeft[domain]
+	if !ok {
+		return
+	}
+	if left > 1 {
+		s.domainsLeft[domain] = left - 1
+		return
+	}
+	delete(s.domainsLeft, domain)
+	s.recorder.add(recordedEvent{Resolved: domain})
+}
+
+// Open a -resume checkpoint to append the hosts of this run to it
+func appendScanRecorder(path string) (*scanRecorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &scanRecorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
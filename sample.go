@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sort"
+)
+
+// Number of addresses a target line expands to, computed without enumerating CIDR ranges
+func lineSize(line string, skipEdges bool) (int64, bool) {
+	if _, ipNet, err := net.ParseCIDR(line); err == nil {
+		ones, bits := ipNet.Mask.Size()
+		if bits-ones > 62 {
+			return 0, false
+		}
+		if start, end := cidrRange(ipNet, skipEdges); bits == 32 {
+			return rangeSize(start, end), true
+		}
+		return 1 << (bits - ones), true
+	}
+	if start, end, ok := parseIPRange(line); ok {
+		return rangeSize(start, end), true
+	}
+	if net.ParseIP(line) != nil || isDomain(line) || isHostPort(line) {
+		return 1, true
+	}
+	return 0, false
+}
+
+// Number of addresses the target lines expand to, before exclusions and duplicates are taken out
+func expandedSize(lines []string, skipEdges bool) int64 {
+	var total int64
+	for _, line := range lines {
+		size, ok := lineSize(line, skipEdges)
+		if _, _, err := net.ParseCIDR(line); (!ok && err == nil) || size > math.MaxInt64-total {
+			return math.MaxInt64 // Too large to count
+		}
+		total += size
+	}
+	return total
+}
+
+// Draw k hosts uniformly without replacement from the distinct addresses of the target lines, after the
+// exclusions are taken out, using Floyd's algorithm over host indexes so only the sample is held in memory.
+// Ranges that overlap are merged first, so no address is drawn twice or weighs double. Domains and IP:PORT
+// lines count as one host each, as they are before resolution; invalid lines are kept so they are still
+// reported. Returns the sampled lines and the population size.
+func sampleTargets(rng *rand.Rand, lines []string, k int64, skipEdges bool, excluded *addrSet) ([]string, int64, error) {
+	var spans []addrSpan
+	var others, invalid []string
+	for _, line := range lines {
+		if _, ok := lineSize(line, skipEdges); !ok {
+			if _, _, err := net.ParseCIDR(line); err == nil {
+				return nil, 0, fmt.Errorf("range %s is too large to sample", line)
+			}
+			invalid = append(invalid, line)
+		} else if start, end, ok := lineBounds(line, skipEdges); ok {
+			spans = append(spans, addrSpan{toAddr(start), toAddr(end)})
+		} else {
+			others = append(others, line)
+		}
+	}
+	spans = subtractSpans(mergeSpans(spans), excluded.allSpans())
+
+	var population int64
+	sizes := make([]int64, len(spans))
+	for i, span := range spans {
+		size, ok := spanSize(span)
+		if !ok || size > math.MaxInt64-population {
+			return nil, 0, fmt.Errorf("range %s-%s is too large to sample", span.lo, span.hi)
+		}
+		sizes[i] = size
+		population += size
+	}
+	population += int64(len(others))
+	if k >= population {
+		sampled := make([]string, 0, population)
+		for _, span := range spans {
+			for addr := span.lo; addr.Compare(span.hi) <= 0 && addr.IsValid(); addr = addr.Next() {
+				sampled = append(sampled, addr.String())
+			}
+		}
+		return append(append(sampled, others...), invalid...), population, nil
+	}
+
+	chosen := make(map[int64]bool, k)
+	for j := population - k; j < population; j++ {
+		if t := rng.Int64N(j + 1); chosen[t] {
+			chosen[j] = true
+		} else {
+			chosen[t] = true
+		}
+	}
+	indexes := make([]int64, 0, k)
+	for index := range chosen {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	// Walk the spans once, mapping each sorted index to its address; the indexes past them pick the other lines
+	var sampled []string
+	var offset int64
+	next := 0
+	for i, span := range spans {
+		for ; next < len(indexes) && indexes[next] < offset+sizes[i]; next++ {
+			sampled = append(sampled, addrAt(span.lo, indexes[next]-offset).String())
+		}
+		offset += sizes[i]
+	}
+	for ; next < len(indexes); next++ {
+		sampled = append(sampled, others[indexes[next]-offset])
+	}
+	return append(sampled, invalid...), population, nil
+}
+
+// Number of addresses in a span; false if it does not fit in the low 64 bits of an IPv6 address
+func spanSize(span addrSpan) (int64, bool) {
+	if span.lo.Is4() {
+		lo, hi := span.lo.As4(), span.hi.As4()
+		return int64(binary.BigEndian.Uint32(hi[:])) - int64(binary.BigEndian.Uint32(lo[:])) + 1, true
+	}
+	lo, hi := span.lo.As16(), span.hi.As16()
+	if [8]byte(lo[:8]) != [8]byte(hi[:8]) {
+		return 0, false
+	}
+	size := binary.BigEndian.Uint64(hi[8:]) - binary.BigEndian.Uint64(lo[8:]) + 1
+	return int64(size), size > 0 && size <= math.MaxInt64
+}
+
+// Return the address at the given offset from the start of a span
+func addrAt(start netip.Addr, offset int64) netip.Addr {
+	if start.Is4() {
+		ip := start.As4()
+		binary.BigEndian.PutUint32(ip[:], binary.BigEndian.Uint32(ip[:])+uint32(offset))
+		return netip.AddrFrom4(ip)
+	}
+	ip := start.As16()
+	binary.BigEndian.PutUint64(ip[8:], binary.BigEndian.Uint64(ip[8:])+uint64(offset))
+	return netip.AddrFrom16(ip)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestSampleTargets(t *testing.T) {
+	excluded := newAddrSet()
+	excluded.add("10.0.0.4-10.0.0.5", false)
+	excluded.add("10.0.0.9", false)
+	// The /29 and the range overlap on 10.0.0.6-7, and 10.0.0.1 is listed twice
+	lines := []string{"10.0.0.0/29", "10.0.0.6-10.0.0.9", "10.0.0.1", "example.com", "not a target"}
+
+	tests := []struct {
+		name           string
+		k              int64
+		wantPopulation int64
+		want           []string // nil = only check the size and scope of the sample
+	}{
+		{"whole population", 100, 8, []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.6", "10.0.0.7", "10.0.0.8", "example.com", "not a target"}},
+		{"sample", 5, 8, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, population, err := sampleTargets(rand.New(rand.NewPCG(1, 1)), lines, tt.k, false, excluded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if population != tt.wantPopulation {
+				t.Errorf("population = %d, want %d", population, tt.wantPopulation)
+			}
+			if tt.want != nil && !slices.Equal(sampled, tt.want) {
+				t.Errorf("sample = %v, want %v", sampled, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			if len(sampled) != int(tt.k)+1 || sampled[len(sampled)-1] != "not a target" {
+				t.Errorf("sample = %v, want %d hosts and the invalid line", sampled, tt.k)
+			}
+			hosts := sampled[:len(sampled)-1]
+			for _, host := range hosts {
+				if host == "10.0.0.4" || host == "10.0.0.5" || host == "10.0.0.9" {
+					t.Errorf("excluded host %s sampled", host)
+				}
+			}
+			if len(slices.Compact(slices.Clone(hosts))) != len(hosts) {
+				t.Errorf("sample %v has duplicates", hosts)
+			}
+		})
+	}
+}
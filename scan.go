@@ -0,0 +1,1589 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/ipv4"
+
+	"pinger/netping"
+)
+
+// Options shared by every command that runs a scan
+type scanOptions struct {
+	targetFiles    stringList
+	sequential     bool
+	inputFormat    string
+	filter         string
+	outputFile     string
+	offlineFile    string
+	outputFormat   string
+	verbose        bool
+	noColor        bool
+	rate           int
+	concurrency    int
+	retries        int
+	bandwidth      string
+	jitter         string
+	manifest       string
+	payloadSize    int
+	payload        string
+	iface          string
+	source         string
+	classify       bool
+	probes         int
+	pingCount      int
+	metricsAddr    string
+	apiAddr        string
+	logLevel       string
+	privileged     bool
+	quiet          bool
+	knownHosts     string
+	fragment       bool
+	collectWindow  time.Duration
+	mux            bool
+	dot            string
+	confirm        string
+	archive        string
+	pmtu           bool
+	recount        bool
+	probe          string
+	timeout        time.Duration
+	httpMethod     string
+	httpScheme     string
+	httpUA         string
+	httpHeaders    stringList
+	tcpPorts       string
+	tcpFallback    string
+	breakerLimit   float64
+	breakerPause   time.Duration
+	portWorkers    int
+	ports          string
+	connectTimeout time.Duration
+	deadline       time.Duration
+	retryBackoff   string
+	sockets        int
+	record         string
+	sqlite         string
+	baseline       string
+	sample         int64
+	live           bool
+	progressJSON   string
+	liveFormat     string
+	scannerID      string
+	rateProfile    string
+	perHostDir     string
+	autoIntensity  bool
+	adaptive       bool
+	randomize      bool
+	seed           uint64
+	sort           bool
+	arp            bool
+	ttl            int
+	firstOnly      bool
+	config         string
+	maxLoss        string
+	groupByDomain  bool
+	ipv6           bool
+	ipv6HostLimit  int64
+	maxHosts       int64
+	skipEdges      bool
+	keepDuplicates bool
+	exclude        stringList
+	excludeFiles   stringList
+	dryRun         bool
+	strict         bool
+	listHosts      bool
+	resolveAll     bool
+	resolvePTR     bool
+	replay         string
+	resume         string
+	pmtuProbes     int
+
+	trackHosts bool // Collect the state of every host in the summary, for watch mode to report transitions
+
+	flags *flag.FlagSet // Flag set the options were registered on, recorded in the manifest
+}
+
+// Results of a completed scan
+type scanSummary struct {
+	alive       int32
+	offline     int32
+	retried     int32           // Alive hosts that only replied after a retry
+	interrupted bool            // Stopped early by Ctrl-C (not by the -deadline); counts cover only the hosts probed so far
+	hosts       map[string]bool // Whether each probed IP was alive, if the options ask to track hosts
+}
+
+// Shared state of a running scan
+type scanState struct {
+	opts        *scanOptions
+	ctx         context.Context // Cancelled by Ctrl-C to stop launching probes
+	pinger      *netping.Pinger
+	http        *httpProber              // HTTP prober in -probe http mode (nil = ICMP)
+	tcp         *tcpProber               // Port checker in -probe tcp mode (nil = ICMP)
+	fallback    *tcpProber               // Port checker for hosts that ignore ICMP with -tcp-ports (nil = ICMP only)
+	portScan    *tcpProber               // Port checker for alive hosts with -ports (nil = none)
+	timeouts    map[string]time.Duration // -timeout overrides by target line, from timeout= annotations
+	mtu         int                      // Upper bound of the -pmtu search (MTU of the outgoing interface)
+	source      string                   // Bound source address, tagged on influx records
+	writer      *bufio.Writer
+	offline     *bufio.Writer     // Hosts that did not respond, with -offline-file (nil = disabled)
+	writeMu     sync.Mutex        // Serializes writes and flushes of writer and offline
+	known       *knownHosts       // Hosts seen in previous runs (nil = output every host)
+	sem         chan struct{}     // Concurrency pool shared by every target file
+	confirm     confirmer         // Second probe for hosts that look alive (nil = trust the first reply)
+	archive     *scanArchive      // Complete scan record bundled with -archive (nil = disabled)
+	population  int64             // Hosts the -sample was drawn from (0 = no sampling)
+	rng         *rand.Rand        // Source of the -sample draw and the -randomize order, seeded with -seed
+	recorder    *scanRecorder     // Raw results saved with -record (nil = disabled)
+	replaying   bool              // Recorded events are being fed through the output pipeline rather than probed
+	domainsLeft map[string]int    // Addresses of each resolved domain still to finish, for the -resume checkpoint (nil = not resuming)
+	sqlite      *sqliteStore      // Results logged to a database with -sqlite (nil = disabled)
+	perHost     *perHostWriter    // One file per alive host with -per-host-dir (nil = disabled)
+	tuner       *intensityTuner   // Rate auto-tuner for -auto-intensity (nil = fixed rate)
+	invalidIn   map[string]string // Files listing each invalid target line
+	seen        *addrSet          // Addresses of the target lines launched so far (nil = probe duplicates with -keep-duplicates)
+	listed      *addrSet          // Addresses of every IP and range target line, which a domain's addresses defer to (nil = -keep-duplicates)
+	resolvedIPs map[string]bool   // Addresses of the domains resolved so far, guarded by mu
+	excluded    *addrSet          // Addresses that must never be probed (nil = nothing excluded)
+	finished    *addrSet          // Addresses a -resume checkpoint already has results for (nil = not resuming)
+	colors      bool              // Color verbose host lines: stdout is a terminal and -no-color is not set
+
+	// Per-host results, collected for structured output formats
+	mu         sync.Mutex
+	results    []Result
+	tierCounts map[string]int32  // Hosts per tier in -classify mode
+	ambiguous  []Result          // Results held back for -recount
+	liveHosts  map[string]string // Output lines of the alive hosts printed by -live, by IP, written sorted at the end
+	hostStates map[string]bool   // Whether each host was alive, by IP (nil unless trackHosts)
+	unresolved []string          // Domains that did not resolve, listed in the summary with -verbose
+
+	// Use atomic counters for alive and not alive hosts
+	aliveCount       int32
+	notAliveCount    int32
+	retriedCount     int32 // Alive hosts that needed more than one attempt
+	fragFailed       int32 // Alive hosts that ignored fragmented probes
+	unconfirmedCount int32 // Hosts that replied but failed confirmation
+	recountAlive     int32 // Ambiguous hosts confirmed alive by -recount
+	recountDead      int32 // Ambiguous hosts found dead by -recount
+	progressCount    int32 // Counter for progress tracking
+	totalHosts       int32 // Hosts to probe; grows when a domain resolves to several addresses
+	duplicates       int32 // Addresses listed more than once, probed only the first time
+	excludedCount    int32 // Addresses left out by -exclude and -exclude-file
+	unresolvedCount  int32 // Domains that did not resolve, also counted offline
+	sentCount        int64 // Echo requests sent with -ping-count
+	receivedCount    int64 // Echo replies received with -ping-count
+	firstOnlySkipped int32 // Hosts left unprobed because their block already had an alive host
+
+	elapsed time.Duration // Wall time of the scan, set when it finishes
+}
+
+// Register the scan flags on a command's flag set
+func addScanFlags(fs *flag.FlagSet) *scanOptions {
+	opts := &scanOptions{flags: fs}
+	fs.StringVar(&opts.config, "config", "", "Specify a YAML or TOML file of default flag values, keyed by flag name; command-line flags override it")
+	fs.Var(&opts.targetFiles, "target-file", "Specify a file containing a list of IP addresses, networks, or domains (one per line), or a previous JSON output; a directory or a pattern such as targets/*.txt reads every matching file, and - reads standard input (repeatable)")
+	fs.BoolVar(&opts.sequential, "sequential", false, "Enable scanning multiple target files one at a time with a summary per file, sharing the rate limit and concurrency pool")
+	fs.BoolVar(&opts.strict, "strict", false, "Enable aborting before any probe is sent if a target file has an invalid line")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Enable counting the hosts to probe and estimating the run time without sending any packets, then exit")
+	fs.BoolVar(&opts.listHosts, "list", false, "Enable printing every address to probe with -dry-run")
+	fs.Var(&opts.exclude, "exclude", "Specify an IP address, CIDR range, or START-END range that must never be probed (repeatable)")
+	fs.Var(&opts.excludeFiles, "exclude-file", "Specify a file of IP addresses and ranges that must never be probed, one per line (repeatable)")
+	fs.BoolVar(&opts.firstOnly, "first-only", false, "Enable stopping the probes of a CIDR block or START-END range once one of its hosts is alive, to map which subnets are populated")
+	fs.IntVar(&opts.ttl, "ttl", 0, "Specify the TTL (IPv6 hop limit) of outgoing echo requests, 1-255 (0 = OS default)")
+	fs.BoolVar(&opts.arp, "arp", false, "Enable discovering hosts on directly attached IPv4 subnets with ARP requests, reporting their MAC addresses; other hosts are probed with ICMP (Linux and Windows)")
+	fs.BoolVar(&opts.sort, "sort", false, "Enable writing the results sorted numerically by IP once the scan finishes, instead of streaming them as hosts finish")
+	fs.BoolVar(&opts.randomize, "randomize", false, "Enable probing the expanded targets in random order instead of range by range, to spread the load across subnets")
+	fs.BoolVar(&opts.keepDuplicates, "keep-duplicates", false, "Enable probing an address every time the target lines list it, skipping the dedup pass that tracks addresses already covered")
+	fs.BoolVar(&opts.skipEdges, "skip-network-broadcast", false, "Enable skipping the network and broadcast addresses of IPv4 CIDR blocks (/31 and /32 blocks are kept whole)")
+	fs.StringVar(&opts.inputFormat, "input-format", "auto", "Specify the target file format: auto, text, or json")
+	fs.StringVar(&opts.filter, "filter", "all", "Specify which hosts to import from a JSON target file: all, alive, or dead")
+	fs.StringVar(&opts.outputFile, "output-file", "alive-hosts.txt", "Specify the output file to save alive hosts (- for stdout, with progress and summary on stderr)")
+	fs.StringVar(&opts.offlineFile, "offline-file", "", "Specify a file to save hosts that did not respond and domains that did not resolve")
+	fs.StringVar(&opts.outputFormat, "output-format", "text", "Specify the output format: text (alive hosts, one per line), json (array of every host result), jsonl (one result per line, written as hosts finish), csv (one row per host), or influx (InfluxDB line protocol, appended as hosts finish)")
+	fs.Int64Var(&opts.maxHosts, "max-hosts", 1<<20, "Specify the most hosts a scan may expand to before it refuses to start, as a guard against a mistyped prefix (0 = no limit)")
+	fs.Int64Var(&opts.ipv6HostLimit, "ipv6-host-limit", 256, "Specify the most addresses an IPv6 CIDR range may expand to; larger prefixes are refused (256 = a /120)")
+	fs.BoolVar(&opts.ipv6, "ipv6", false, "Enable resolving domains to IPv6 (AAAA) addresses when they have no IPv4 address, or alongside them with -resolve-all")
+	fs.BoolVar(&opts.resolveAll, "resolve-all", false, "Enable probing every address a domain resolves to, each reported as its own host with the domain as resolved_from")
+	fs.BoolVar(&opts.resolvePTR, "resolve-ptr", false, "Enable reverse-DNS lookups of alive hosts, adding their names to the output")
+	fs.BoolVar(&opts.groupByDomain, "group-by-domain", false, "Enable probing every address a domain resolves to and writing one JSON record per domain with its address results nested (requires -output-format json)")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Enable verbose output to print results to the console")
+	fs.BoolVar(&opts.noColor, "no-color", false, "Enable plain verbose output without ANSI colors (colors are already off when stdout is not a terminal)")
+	fs.BoolVar(&opts.privileged, "privileged", true, "Enable raw ICMP sockets; false sends echo requests from unprivileged datagram sockets, which are also used automatically when raw sockets are not permitted")
+	fs.StringVar(&opts.logLevel, "log-level", "info", "Specify the minimum level of log messages: error, warn, info, or debug (per-host send errors)")
+	fs.BoolVar(&opts.quiet, "quiet", false, "Enable printing nothing but the final summary and fatal errors")
+	fs.StringVar(&opts.liveFormat, "live-format", "text", "Specify the format of -live lines: text (as in the output file) or json (one result object per line)")
+	fs.StringVar(&opts.progressJSON, "progress-json", "", "Specify where to write progress events as JSON Lines instead of the progress line: - for stderr, or a file such as /dev/fd/3")
+	fs.BoolVar(&opts.live, "live", false, "Enable printing each alive host to stdout as it is found (progress and summary go to stderr); text output is written sorted and deduplicated at the end")
+	fs.IntVar(&opts.rate, "rate", defaultRate, "Specify the maximum number of probes sent per second (0 = unlimited)")
+	fs.IntVar(&opts.concurrency, "concurrency", concurrentLimit, "Specify the maximum number of hosts probed at once")
+	fs.IntVar(&opts.retries, "retries", maxRetries, "Specify the number of attempts per host before it is reported offline")
+	fs.StringVar(&opts.rateProfile, "rate-profile", "", "Specify a file of 'HH:MM-HH:MM RATE' lines setting the packet rate by local time of day (-rate applies outside the ranges)")
+	fs.BoolVar(&opts.autoIntensity, "auto-intensity", false, "Enable raising the packet rate from -rate while reply loss stays under -max-loss, then holding it")
+	fs.BoolVar(&opts.adaptive, "adaptive", false, "Enable backing off from -rate while reply loss is over -max-loss, and speeding back up to -rate as replies return")
+	fs.StringVar(&opts.maxLoss, "max-loss", "2%", "Specify the reply loss ceiling for -auto-intensity and -adaptive, e.g. 2% or 0.02")
+	fs.StringVar(&opts.jitter, "jitter", "", "Specify a random variation of the gap between probes, e.g. 30% (or ±30%), keeping the average near -rate so probes do not leave at a fixed cadence")
+	fs.StringVar(&opts.bandwidth, "bandwidth", "", "Specify the maximum probe bandwidth, e.g. 1mbps or 500kbps (the more restrictive of -rate and -bandwidth applies)")
+	fs.StringVar(&opts.probe, "probe", "icmp", "Specify the probe type: icmp, tcp, or http")
+	fs.StringVar(&opts.tcpPorts, "tcp", "", "Specify TCP ports to check on every host, e.g. 22,80,443,3389 (implies -probe tcp; default "+defaultTCPPorts+")")
+	fs.StringVar(&opts.tcpFallback, "tcp-ports", "", "Specify TCP ports to try on hosts that do not answer ICMP, e.g. 80,443,22 (alive if any port answers)")
+	fs.IntVar(&opts.portWorkers, "port-concurrency", 4, "Specify the number of ports checked at once per host in TCP mode or with -ports")
+	fs.StringVar(&opts.ports, "ports", "", "Specify TCP ports to check on every alive host once it answers, e.g. 22,80,443,3389 (open ports are reported as open_ports)")
+	fs.DurationVar(&opts.connectTimeout, "connect-timeout", time.Second, "Specify the timeout of each -ports connection attempt")
+	fs.StringVar(&opts.retryBackoff, "retry-backoff", "fixed", "Specify the wait between ICMP attempts: none, fixed (half the timeout), or exp (half the timeout, doubling after each attempt)")
+	fs.DurationVar(&opts.deadline, "deadline", 0, "Specify a time budget for the scan; retries per host shrink as it runs out, and probes still in flight when it ends are abandoned (0 = no budget)")
+	fs.DurationVar(&opts.timeout, "timeout", icmpTimeout, "Specify how long to wait for each reply, e.g. 500ms or 3s (covers the whole request in HTTP mode)")
+	fs.StringVar(&opts.httpMethod, "http-method", "HEAD", "Specify the HTTP probe method: HEAD or GET")
+	fs.StringVar(&opts.httpScheme, "http-scheme", "http", "Specify the HTTP probe scheme: http or https")
+	fs.StringVar(&opts.httpUA, "http-ua", "", "Specify the User-Agent sent by the HTTP probe")
+	fs.Var(&opts.httpHeaders, "http-header", "Specify an extra header for the HTTP probe as 'Name: value' (repeatable)")
+	fs.Float64Var(&opts.breakerLimit, "breaker-threshold", 0.5, "Specify the fraction of failed sends over 5s that pauses the scan and halves the rate (0 = disable the circuit breaker)")
+	fs.DurationVar(&opts.breakerPause, "breaker-cooldown", 10*time.Second, "Specify how long sending pauses when the circuit breaker trips")
+	fs.StringVar(&opts.payload, "payload", icmpPayload, "Specify the text at the start of every ICMP payload, e.g. to spot probes in packet captures")
+	opts.payloadSize = -1 // Follow the -payload length unless a size is given
+	setSize := func(value string) error {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 || size > maxPayloadSize {
+			return fmt.Errorf("must be between 0 and %d", maxPayloadSize)
+		}
+		opts.payloadSize = size
+		return nil
+	}
+	fs.Func("payload-size", "Specify the ICMP payload size in `bytes`, padding or truncating -payload (default: length of -payload; clamped to fit the interface MTU)", setSize)
+	fs.Func("size", "Specify the ICMP payload size in `bytes` (same as -payload-size)", setSize)
+	fs.StringVar(&opts.iface, "interface", "", "Specify the network interface to send probes from (e.g. eth0, tun0)")
+	fs.StringVar(&opts.source, "source", "", "Specify the local address to send probes from (e.g. 192.168.50.10); it must be assigned to an interface of this host")
+	fs.BoolVar(&opts.classify, "classify", false, "Enable classifying each host as responsive, intermittent, filtered, or silent")
+	fs.StringVar(&opts.metricsAddr, "metrics-addr", "", "Specify an address such as :9100 to serve Prometheus metrics on /metrics while scanning")
+	fs.StringVar(&opts.apiAddr, "api-addr", "", "Specify an address such as :8080 to serve the alive and offline hosts on /results and progress counts on /status as JSON, while scanning and after the scan finishes until Ctrl-C")
+	fs.IntVar(&opts.pingCount, "ping-count", 0, "Specify the number of echo requests sent to each host to measure packet loss and RTT min/avg/max, like ping -c (0 = stop at the first reply)")
+	fs.IntVar(&opts.probes, "classify-probes", 4, "Specify the number of probes sent to each host in -classify mode")
+	fs.BoolVar(&opts.fragment, "fragment", false, "Enable re-probing alive hosts with fragmented echo requests to test reassembly (requires raw IP sockets: root/CAP_NET_RAW)")
+	fs.BoolVar(&opts.recount, "recount", false, "Enable re-probing ambiguous hosts (replied only after retries, or intermittently) with more retries and a longer timeout")
+	fs.BoolVar(&opts.pmtu, "pmtu", false, "Enable discovering the path MTU of each alive host with Don't Fragment probes (requires raw IP sockets: root/CAP_NET_RAW)")
+	fs.IntVar(&opts.pmtuProbes, "pmtu-probes", 8, "Specify the maximum number of probes per host for -pmtu")
+	fs.DurationVar(&opts.collectWindow, "collect-window", 0, "Specify a grace window to keep collecting replies after the last probe; enables sending every probe from one shared socket (0 = per-host timeouts)")
+	fs.BoolVar(&opts.mux, "mux", false, "Enable sending every probe from one sender loop on the shared socket, with replies matched by sequence number and timeouts expired by a sweeper, instead of a goroutine per host (-concurrency is ignored)")
+	fs.IntVar(&opts.sockets, "sockets", 1, "Specify the number of ICMP sockets a -collect-window sweep spreads its targets across, each with its own echo ID")
+	fs.Int64Var(&opts.sample, "sample", 0, "Specify a number of hosts to draw uniformly at random from all targets instead of probing every host (0 = probe all)")
+	fs.Uint64Var(&opts.seed, "seed", 0, "Specify the random seed of -sample and -randomize, to repeat a scan's sample and order (0 = a new seed each scan, recorded in the manifest)")
+	fs.StringVar(&opts.confirm, "confirm", "", "Specify a method to confirm alive hosts before reporting them: icmp or tcp:PORT[,PORT...]")
+	fs.StringVar(&opts.archive, "archive", "", "Specify a zip file to bundle the alive and dead lists, errors, RTT histogram, manifest, and summary into")
+	fs.StringVar(&opts.perHostDir, "per-host-dir", "", "Specify a directory to write each alive host's result to as its own <ip>.json file")
+	fs.StringVar(&opts.dot, "dot", "", "Specify a file to write a Graphviz/DOT reachability graph to")
+	fs.StringVar(&opts.knownHosts, "known-hosts", "", "Specify a known-hosts file; only hosts never seen before are output, then added to it")
+	fs.StringVar(&opts.record, "record", "", "Specify a file to record every raw host result to, for replaying later")
+	fs.StringVar(&opts.baseline, "baseline", "", "Specify a previous output file (text or JSON) to compare the alive hosts of this scan against")
+	fs.StringVar(&opts.sqlite, "sqlite", "", "Specify a SQLite database file to log every host result to, one scan per run, for querying availability over time")
+	fs.StringVar(&opts.resume, "resume", "", "Specify a checkpoint file that every finished host is appended to; if an interrupted scan left one behind, its hosts are restored instead of probed again. Removed once the scan completes")
+	fs.StringVar(&opts.replay, "replay", "", "Specify a -record file to feed through the output pipeline instead of probing")
+	fs.StringVar(&opts.scannerID, "scanner-id", defaultScannerID(), "Specify the identity of this scanner, stamped on every structured result and in the manifest")
+	fs.StringVar(&opts.manifest, "manifest", "", "Specify a file to write a JSON run manifest to (configuration, target checksums, timing)")
+	return opts
+}
+
+// Target lines read from one target file
+type targetBatch struct {
+	name  string
+	lines []string
+}
+
+// Scan every target in the target files and write alive hosts to the output file
+func runScan(opts *scanOptions) scanSummary {
+	startTime := time.Now()
+
+	if len(opts.targetFiles) == 0 && opts.replay == "" {
+		log.Fatal("Error: -target-file flag is required")
+	}
+	if opts.rate < 0 {
+		log.Fatal("Error: -rate must not be negative")
+	}
+	if opts.concurrency < 1 {
+		log.Fatal("Error: -concurrency must be at least 1")
+	}
+	if opts.retries < 1 {
+		log.Fatal("Error: -retries must be at least 1")
+	}
+	if opts.payloadSize < 0 {
+		opts.payloadSize = min(len(opts.payload), maxPayloadSize)
+	}
+	if opts.classify && opts.probes < 1 {
+		log.Fatal("Error: -classify-probes must be at least 1")
+	}
+	if opts.pingCount < 0 {
+		log.Fatal("Error: -ping-count must not be negative")
+	}
+	if opts.arp && (opts.probe != "icmp" || opts.classify || opts.collectWindow > 0 || opts.pingCount > 0 || opts.fragment || opts.pmtu) {
+		log.Fatal("Error: -arp cannot be combined with -probe tcp/http, -classify, -collect-window, -ping-count, -fragment, or -pmtu")
+	}
+	if opts.pingCount > 0 && (opts.probe != "icmp" || opts.classify || opts.collectWindow > 0) {
+		log.Fatal("Error: -ping-count cannot be combined with -probe tcp/http, -classify, or -collect-window")
+	}
+	if opts.collectWindow < 0 {
+		log.Fatal("Error: -collect-window must not be negative")
+	}
+	if opts.collectWindow > 0 && (opts.classify || opts.fragment || opts.pmtu) {
+		log.Fatal("Error: -collect-window cannot be combined with -classify, -fragment, or -pmtu")
+	}
+	if opts.firstOnly && (opts.collectWindow > 0 || opts.randomize) {
+		log.Fatal("Error: -first-only cannot be combined with -collect-window or -randomize")
+	}
+	if opts.sockets < 1 {
+		log.Fatal("Error: -sockets must be at least 1")
+	}
+	if opts.sockets > 1 && opts.collectWindow == 0 {
+		log.Fatal("Error: -sockets requires -collect-window")
+	}
+	if opts.groupByDomain && opts.outputFormat != "json" {
+		log.Fatal("Error: -group-by-domain requires -output-format json")
+	}
+	if opts.groupByDomain {
+		opts.resolveAll = true // Grouping needs every address of each domain
+	}
+	if opts.quiet && (opts.verbose || opts.live) {
+		log.Fatal("Error: -quiet cannot be combined with -verbose or -live")
+	}
+	if opts.quiet {
+		opts.logLevel = "error"
+	}
+	if err := setLogLevel(opts.logLevel); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if opts.live && opts.verbose {
+		log.Fatal("Error: -live cannot be combined with -verbose")
+	}
+	if opts.sort && opts.outputFormat == "influx" {
+		log.Fatal("Error: -sort does not apply to -output-format influx, whose points are ordered by timestamp")
+	}
+	if opts.outputFile == stdoutPath && (opts.verbose || opts.live) {
+		log.Fatal("Error: -output-file - cannot be combined with -verbose or -live, which also print to stdout")
+	}
+	if opts.source != "" && opts.iface != "" {
+		log.Fatal("Error: -source cannot be combined with -interface")
+	}
+	if opts.liveFormat != "text" && opts.liveFormat != "json" {
+		log.Fatalf("Error: unknown live format '%s' (expected text or json)\n", opts.liveFormat)
+	}
+	if opts.sample < 0 {
+		log.Fatal("Error: -sample must not be negative")
+	}
+	if opts.maxHosts < 0 {
+		log.Fatal("Error: -max-hosts must not be negative")
+	}
+	if opts.ipv6HostLimit < 1 {
+		log.Fatal("Error: -ipv6-host-limit must be at least 1")
+	}
+	if opts.sample > 0 && opts.sequential {
+		log.Fatal("Error: -sample cannot be combined with -sequential")
+	}
+	if opts.tcpPorts != "" && opts.probe == "icmp" {
+		opts.probe = "tcp"
+	}
+	if opts.probe != "icmp" && opts.probe != "tcp" && opts.probe != "http" {
+		log.Fatalf("Error: unknown probe type '%s'\n", opts.probe)
+	}
+	if opts.probe != "icmp" && (opts.classify || opts.recount || opts.collectWindow > 0) {
+		log.Fatalf("Error: -probe %s cannot be combined with -classify, -recount, or -collect-window\n", opts.probe)
+	}
+	if opts.tcpFallback != "" && (opts.probe != "icmp" || opts.classify || opts.collectWindow > 0) {
+		log.Fatal("Error: -tcp-ports cannot be combined with -probe tcp/http, -classify, or -collect-window")
+	}
+	if opts.mux && (opts.probe != "icmp" || opts.classify || opts.collectWindow > 0 || opts.pingCount > 0 || opts.fragment || opts.pmtu || opts.tcpFallback != "" || opts.arp || opts.firstOnly) {
+		log.Fatal("Error: -mux cannot be combined with -probe tcp/http, -classify, -collect-window, -ping-count, -fragment, -pmtu, -tcp-ports, -arp, or -first-only")
+	}
+	if opts.breakerLimit < 0 || opts.breakerLimit > 1 {
+		log.Fatal("Error: -breaker-threshold must be between 0 and 1")
+	}
+	if opts.deadline < 0 {
+		log.Fatal("Error: -deadline must not be negative")
+	}
+	if opts.ports != "" && opts.probe == "tcp" {
+		log.Fatal("Error: -ports cannot be combined with -probe tcp, which already checks the -tcp ports of every host")
+	}
+	if opts.resume != "" && (opts.record != "" || opts.replay != "" || opts.sample > 0) {
+		log.Fatal("Error: -resume cannot be combined with -record, -replay, or -sample")
+	}
+	if opts.ttl < 0 || opts.ttl > 255 {
+		log.Fatal("Error: -ttl must be between 1 and 255, or 0 for the OS default")
+	}
+	if opts.connectTimeout <= 0 {
+		log.Fatal("Error: -connect-timeout must be positive")
+	}
+	if opts.portWorkers < 1 {
+		log.Fatal("Error: -port-concurrency must be at least 1")
+	}
+	if opts.timeout <= 0 {
+		log.Fatal("Error: -timeout must be positive")
+	}
+	if opts.pmtu && opts.pmtuProbes < 1 {
+		log.Fatal("Error: -pmtu-probes must be at least 1")
+	}
+	if !slices.Contains([]string{"text", "json", "jsonl", "csv", "influx"}, opts.outputFormat) {
+		log.Fatalf("Error: unknown output format '%s'\n", opts.outputFormat)
+	}
+	bandwidth, err := netping.ParseBandwidth(opts.bandwidth)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	jitter, err := netping.ParseJitter(opts.jitter)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	maxLoss, err := parseLoss(opts.maxLoss)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if opts.autoIntensity && (opts.rate == 0 || opts.rateProfile != "" || opts.collectWindow > 0) {
+		log.Fatal("Error: -auto-intensity needs a starting -rate and cannot be combined with -rate-profile or -collect-window")
+	}
+	if opts.adaptive && (opts.autoIntensity || opts.rate == 0 || opts.rateProfile != "" || opts.collectWindow > 0) {
+		log.Fatal("Error: -adaptive needs a base -rate and cannot be combined with -auto-intensity, -rate-profile, or -collect-window")
+	}
+
+	// Keep stdout for alive hosts only in -live mode, or for the results when they are written to stdout
+	var console io.Writer = os.Stdout
+	if opts.live || opts.outputFile == stdoutPath {
+		console = os.Stderr
+	}
+	notes := noteWriter(console, opts.quiet)
+
+	// Read the target lines of every file; directories and patterns are expanded on every scan of a watch, so new files are picked up
+	targetFiles, err := expandTargetFiles(opts.targetFiles)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	var batches []targetBatch
+	var lines []string
+	for _, targetFile := range targetFiles {
+		fileLines, err := loadTargets(targetFile, opts.inputFormat, opts.filter)
+		if err != nil {
+			log.Fatalf("Error reading file '%s': %v\n", targetFile, err)
+		}
+		batches = append(batches, targetBatch{name: targetFile, lines: fileLines})
+		lines = append(lines, fileLines...)
+	}
+
+	// Read the checkpoint of an interrupted scan, and drop the domain and IP:PORT lines it already covers
+	var resumed *checkpoint
+	var checkpointRecorder *scanRecorder
+	if opts.resume != "" {
+		if resumed, err = loadCheckpoint(opts.resume); err != nil {
+			log.Fatalf("Error reading checkpoint '%s': %v\n", opts.resume, err)
+		}
+		if checkpointRecorder, err = appendScanRecorder(opts.resume); err != nil {
+			log.Fatalf("Error opening checkpoint '%s': %v\n", opts.resume, err)
+		}
+		if len(resumed.events) > 0 {
+			fmt.Fprintf(notes, "Resuming from checkpoint '%s': %d hosts already scanned\n", opts.resume, len(resumed.events))
+		}
+		lines = resumed.remaining(lines)
+		for i := range batches {
+			batches[i].lines = resumed.remaining(batches[i].lines)
+		}
+	}
+
+	// Read the per-line timeout= overrides
+	timeouts, err := loadTimeoutOverrides(targetFiles, opts.inputFormat)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if len(timeouts) > 0 && (opts.collectWindow > 0 || opts.mux) {
+		warnLog.Println("timeout= annotations are ignored by -collect-window and -mux, which wait -timeout for every host")
+	}
+
+	// Refuse IPv6 prefixes too large to enumerate before anything expands them
+	for _, line := range lines {
+		if err := checkIPv6Range(line, opts.ipv6HostLimit); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+
+	// Read the baseline before the output file, which may be the same file, is overwritten
+	var baseline []string
+	if opts.baseline != "" {
+		if baseline, err = loadAliveSet(opts.baseline); err != nil {
+			log.Fatalf("Error reading baseline '%s': %v\n", opts.baseline, err)
+		}
+		opts.trackHosts = true
+	}
+	if opts.apiAddr != "" {
+		opts.trackHosts = true // The API lists hosts from the state collected for every host
+	}
+
+	// One seeded source for every random choice, so a scan can be repeated with its recorded -seed
+	seed := opts.seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	// Load the addresses that are out of scope
+	excluded, err := loadExclusions(opts)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+
+	// Draw the sample from the addresses in scope before anything expands the target ranges
+	var population int64
+	if opts.sample > 0 {
+		if lines, population, err = sampleTargets(rng, lines, opts.sample, opts.skipEdges, excluded); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		if opts.sample < population {
+			fmt.Fprintf(notes, "Probing a uniform random sample of %d of the %d distinct hosts in scope (%.4f%%)\n", opts.sample, population, float64(opts.sample)*100/float64(population))
+		}
+	}
+
+	// Refuse a scan that expands past -max-hosts before any file is opened
+	if opts.maxHosts > 0 {
+		if expanded := expandedSize(lines, opts.skipEdges); expanded > opts.maxHosts {
+			log.Fatalf("Error: the targets expand to %d hosts, more than -max-hosts %d (check for a mistyped prefix, or raise -max-hosts)\n", expanded, opts.maxHosts)
+		}
+	}
+
+	// Stop after the counting pass with -dry-run
+	invalid := findInvalidTargets(batches)
+	invalidIn := invalidLineSources(invalid)
+	if opts.dryRun {
+		dryRun(opts, lines, invalidIn, excluded)
+	}
+
+	// Report bad target lines before any probe is sent; -strict refuses to scan at all
+	if len(invalid) > 0 && opts.strict {
+		reportInvalidTargets(errorLog, invalid)
+		log.Fatal("Error: the target files contain invalid lines; no hosts were probed (-strict)")
+	} else if len(invalid) > 0 {
+		reportInvalidTargets(warnLog, invalid)
+	}
+
+	// Without -sequential, files are merged and scanned together
+	if !opts.sequential {
+		batches = []targetBatch{{name: strings.Join(targetFiles, ", "), lines: lines}}
+	}
+
+	// Bind to the chosen interface or address and fit the payload to the outgoing MTU
+	source, payloadSize, mtu := opts.source, opts.payloadSize, netping.DefaultMTU
+	var link *net.Interface
+	if opts.source != "" {
+		link, err = sourceInterface(opts.source)
+	} else {
+		link, err = outgoingInterface(opts.iface, lines)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if link != nil {
+		if opts.iface != "" {
+			if source, err = interfaceIPv4(link); err != nil {
+				log.Fatalf("Error: %v\n", err)
+			}
+		}
+		if opts.verbose {
+			fmt.Fprintf(notes, "Outgoing interface %s, MTU %d\n", link.Name, link.MTU)
+		}
+		if link.MTU > 0 {
+			mtu = link.MTU
+		}
+		if maxFit := link.MTU - ipv4.HeaderLen - netping.ICMPHeaderLen; link.MTU > 0 && payloadSize > maxFit {
+			warnLog.Printf("Warning: payload size %d exceeds the %s MTU of %d, clamping to %d bytes\n", payloadSize, link.Name, link.MTU, maxFit)
+			payloadSize = maxFit
+		}
+	}
+
+	// Load the hosts seen in previous runs
+	var known *knownHosts
+	if opts.knownHosts != "" {
+		if known, err = loadKnownHosts(opts.knownHosts); err != nil {
+			log.Fatalf("Error reading known-hosts file '%s': %v\n", opts.knownHosts, err)
+		}
+	}
+
+	// Checksum the target files before scanning so the manifest records what was actually read
+	var manifest *Manifest
+	if opts.manifest != "" || opts.archive != "" {
+		manifest = newManifest(opts.flags, opts.scannerID, seed, startTime)
+		for _, targetFile := range targetFiles {
+			if err := manifest.addTargetSource(targetFile); err != nil {
+				log.Fatalf("Error reading file '%s': %v\n", targetFile, err)
+			}
+		}
+	}
+
+	// Stop launching probes on Ctrl-C, SIGTERM, or the -deadline, then write whatever was collected
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.deadline > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeout(ctx, opts.deadline)
+		defer stop()
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			infoLog.Println("Interrupted: finishing in-flight probes and writing partial results (press Ctrl-C again to quit immediately)")
+			signal.Stop(signals) // A second Ctrl-C falls through to the default handler
+			cancel()
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				infoLog.Println("Deadline reached: abandoning in-flight probes and writing partial results")
+			}
+		}
+	}()
+
+	// Open the ICMP socket up front, so missing privileges stop the scan instead of reporting every host offline
+	pinger := netping.NewPinger(netping.NewLimiter(opts.rate, bandwidth), opts.payload, payloadSize, source) // Rate limiter applies to every probe packet sent
+	pinger.Limiter.SetJitter(jitter)
+	if !opts.privileged {
+		pinger.UseDatagramSockets()
+	}
+	pinger.SetTTL(opts.ttl)
+	if opts.probe == "icmp" {
+		datagram, err := pinger.OpenSocket(net.IPv4zero)
+		if err != nil {
+			log.Fatalf("Error: cannot open an ICMP socket: %v\nRun NetPing as root/Administrator, grant it raw socket access (sudo setcap cap_net_raw+ep netping), or allow unprivileged ping for your group (sysctl net.ipv4.ping_group_range)\n", err)
+		}
+		if datagram && (opts.collectWindow > 0 || opts.fragment || opts.pmtu) {
+			log.Fatal("Error: -collect-window, -fragment, and -pmtu need raw socket privileges")
+		}
+		if datagram && opts.privileged {
+			infoLog.Println("No raw socket privileges: sending echo requests from unprivileged datagram sockets")
+		}
+	}
+	if opts.arp {
+		if err := netping.CheckARP(); err != nil {
+			log.Fatalf("Error: -arp cannot send ARP requests (run NetPing as root/Administrator, or grant it raw socket access): %v\n", err)
+		}
+	}
+
+	// Open the output file for writing; influx records are appended so repeated scans build a series
+	fileFlags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.outputFormat == "influx" {
+		fileFlags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	outputFile := os.Stdout
+	if opts.outputFile != stdoutPath {
+		file, err := os.OpenFile(opts.outputFile, fileFlags, 0666)
+		if err != nil {
+			log.Fatalf("Error creating output file '%s': %v\n", opts.outputFile, err)
+		}
+		defer file.Close()
+		outputFile = file
+	}
+	var offlineWriter *bufio.Writer
+	if opts.offlineFile != "" {
+		offlineFile, err := os.Create(opts.offlineFile)
+		if err != nil {
+			log.Fatalf("Error creating offline file '%s': %v\n", opts.offlineFile, err)
+		}
+		defer offlineFile.Close()
+		offlineWriter = bufio.NewWriter(offlineFile)
+	}
+
+	state := &scanState{
+		opts:       opts,
+		ctx:        ctx,
+		pinger:     pinger,
+		writer:     bufio.NewWriter(outputFile),
+		offline:    offlineWriter,
+		tierCounts: map[string]int32{},
+		liveHosts:  map[string]string{},
+		known:      known,
+		mtu:        mtu,
+		source:     source,
+		population: population,
+		rng:        rng,
+		timeouts:   timeouts,
+		invalidIn:  invalidIn,
+		excluded:   excluded,
+		colors:     !opts.noColor && colorTerminal(os.Stdout),
+		sem:        make(chan struct{}, opts.concurrency), // Use a semaphore to limit the number of concurrent goroutines
+	}
+	if opts.trackHosts {
+		state.hostStates = map[string]bool{}
+	}
+	if opts.metricsAddr != "" {
+		if err := startMetrics(opts.metricsAddr); err != nil {
+			log.Fatalf("Error starting metrics server on '%s': %v\n", opts.metricsAddr, err)
+		}
+		metrics.begin(state)
+	}
+	if opts.apiAddr != "" {
+		if err := startAPI(opts.apiAddr); err != nil {
+			log.Fatalf("Error starting API server on '%s': %v\n", opts.apiAddr, err)
+		}
+		api.begin(state)
+	}
+	state.pinger.Context = state.ctx
+	state.pinger.Timeout = opts.timeout
+	state.pinger.Retries = opts.retries
+	if state.pinger.Backoff, err = netping.ParseBackoff(opts.retryBackoff); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if opts.rateProfile != "" {
+		profile, err := netping.LoadRateProfile(opts.rateProfile)
+		if err != nil {
+			log.Fatalf("Error reading rate profile '%s': %v\n", opts.rateProfile, err)
+		}
+		state.pinger.Limiter.SetProfile(profile)
+	}
+	state.pinger.Log = debugLog // Per-host send errors would flood the console during big scans
+	state.pinger.Breaker = netping.NewBreaker(opts.breakerLimit, opts.breakerPause, state.pinger.Limiter)
+	if state.pinger.Breaker != nil {
+		state.pinger.Breaker.Log = warnLog
+	}
+	if opts.probe == "tcp" {
+		spec := opts.tcpPorts
+		if spec == "" {
+			spec = defaultTCPPorts
+		}
+		ports, err := parsePorts(spec)
+		if err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		state.tcp = &tcpProber{ports: ports, timeout: opts.timeout, concurrency: opts.portWorkers}
+	}
+	if opts.tcpFallback != "" {
+		ports, err := parsePorts(opts.tcpFallback)
+		if err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		state.fallback = &tcpProber{ports: ports, timeout: opts.timeout, concurrency: opts.portWorkers}
+	}
+	if opts.ports != "" {
+		ports, err := parsePorts(opts.ports)
+		if err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		state.portScan = &tcpProber{ports: ports, timeout: opts.connectTimeout, concurrency: opts.portWorkers}
+	}
+	if opts.probe == "http" {
+		if state.http, err = newHTTPProber(opts.httpMethod, opts.httpScheme, opts.httpUA, opts.httpHeaders, opts.timeout, opts.retries); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+	}
+	if state.confirm, err = parseConfirmMethod(opts.confirm); err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	if opts.archive != "" {
+		if state.archive, err = newScanArchive(opts.archive, startTime); err != nil {
+			log.Fatalf("Error creating archive '%s': %v\n", opts.archive, err)
+		}
+	}
+
+	if opts.autoIntensity {
+		state.tuner = newIntensityTuner(state.pinger.Limiter, maxLoss)
+	}
+	if opts.adaptive {
+		state.tuner = newAdaptiveTuner(state.pinger.Limiter, maxLoss)
+	}
+	if opts.perHostDir != "" {
+		if state.perHost, err = newPerHostWriter(opts.perHostDir); err != nil {
+			log.Fatalf("Error creating directory '%s': %v\n", opts.perHostDir, err)
+		}
+	}
+	if opts.record != "" {
+		if state.recorder, err = newScanRecorder(opts.record); err != nil {
+			log.Fatalf("Error creating record file '%s': %v\n", opts.record, err)
+		}
+	}
+	if opts.sqlite != "" {
+		if state.sqlite, err = openSQLiteStore(opts.sqlite, opts.scannerID, startTime); err != nil {
+			log.Fatalf("Error opening database '%s': %v\n", opts.sqlite, err)
+		}
+	}
+
+	// Calculate the total number of hosts
+	var totalHosts int32
+	var counted *addrSet
+	if !opts.keepDuplicates {
+		counted = newAddrSet()
+		state.seen = newAddrSet()
+		state.listed, state.resolvedIPs = counted, map[string]bool{}
+	}
+	for _, line := range lines {
+		count, _ := countHosts(line, opts.skipEdges)
+		excluded, duplicates := skippedHosts(line, opts.skipEdges, state.excluded, counted)
+		finished := resumed.finishedHosts(line, opts.skipEdges, state.excluded, counted)
+		counted.add(line, opts.skipEdges)
+		totalHosts += count - excluded - duplicates - finished
+		state.excludedCount += excluded
+		state.duplicates += duplicates
+	}
+	var replayed []recordedEvent
+	if opts.replay != "" {
+		if replayed, err = readRecordedEvents(opts.replay); err != nil {
+			log.Fatalf("Error reading record file '%s': %v\n", opts.replay, err)
+		}
+		for _, event := range replayed {
+			if event.Invalid == "" {
+				totalHosts++
+			}
+		}
+	}
+	if resumed != nil {
+		state.finished = resumed.hosts
+		totalHosts += int32(len(resumed.events))
+	}
+	state.totalHosts = totalHosts
+	state.pinger.Budget = netping.NewRetryBudget(opts.deadline, opts.concurrency, func() int32 {
+		return atomic.LoadInt32(&state.totalHosts) - atomic.LoadInt32(&state.progressCount)
+	})
+
+	// Open the progress event stream, which replaces the progress line
+	var progressEvents *json.Encoder
+	if opts.progressJSON != "" {
+		progressFile, err := openProgressStream(opts.progressJSON)
+		if err != nil {
+			log.Fatalf("Error opening progress stream '%s': %v\n", opts.progressJSON, err)
+		}
+		if progressFile != os.Stderr {
+			defer progressFile.Close()
+		}
+		progressEvents = json.NewEncoder(progressFile)
+	}
+
+	// Start a goroutine to periodically print progress if verbose is disabled, or to write progress
+	// events even when it is not; it prints the final count once done is closed
+	done := make(chan struct{})
+	progressDone := make(chan struct{})
+	if !opts.verbose || progressEvents != nil {
+		go func() {
+			defer close(progressDone)
+			var lastProgress int32
+			var eta etaEstimator
+			lastRate := state.pinger.Limiter.CurrentRate()
+			eta.observe(0, time.Now())
+			printProgress := func(progress int32, rate int, eta *etaEstimator) {
+				if progressEvents != nil {
+					progressEvents.Encode(progressEvent{progress, atomic.LoadInt32(&state.totalHosts), atomic.LoadInt32(&state.aliveCount)})
+					return
+				}
+				// Show the effective rate when a profile or backoff can change it
+				showRate := opts.rateProfile != "" || rate != opts.rate
+				fmt.Fprint(notes, "\r"+formatProgress(progress, atomic.LoadInt32(&state.totalHosts), rate, showRate, eta))
+			}
+			for {
+				select {
+				case <-done:
+					printProgress(atomic.LoadInt32(&state.progressCount), state.pinger.Limiter.CurrentRate(), nil)
+					return
+				case <-time.After(500 * time.Millisecond):
+				}
+				currentProgress := atomic.LoadInt32(&state.progressCount)
+				currentRate := state.pinger.Limiter.CurrentRate()
+				eta.observe(currentProgress, time.Now())
+				if currentProgress != lastProgress || currentRate != lastRate {
+					printProgress(currentProgress, currentRate, &eta)
+					lastProgress, lastRate = currentProgress, currentRate
+				}
+			}
+		}()
+	} else {
+		close(progressDone)
+	}
+
+	go state.tuner.run(done)
+	go state.flushPeriodically(done)
+
+	// Process each target file, one at a time with -sequential
+	if opts.replay != "" {
+		state.replay(replayed)
+	}
+	if resumed != nil {
+		state.replay(resumed.events)
+		state.recorder = checkpointRecorder // Only hosts probed from now on are appended to the checkpoint
+		state.domainsLeft = map[string]int{}
+	}
+	for _, batch := range batches {
+		if state.interrupted() {
+			break
+		}
+		if !opts.sequential {
+			state.scanLines(batch.lines)
+			break
+		}
+		fmt.Fprintf(console, "\n=== %s ===\n", batch.name)
+		before := state.summary()
+		state.scanLines(batch.lines)
+		after := state.summary()
+		fmt.Fprintf(console, "\nAlive hosts: %d\n", after.alive-before.alive)
+		fmt.Fprintf(console, "Offline hosts: %d\n", after.offline-before.offline)
+	}
+	close(done)
+	<-progressDone // Let the final progress line out before anything else is printed
+	state.elapsed = time.Since(startTime)
+	metrics.finish(state.elapsed)
+	api.finish(state.elapsed)
+	state.pinger.Close()
+	if opts.sequential {
+		fmt.Fprintf(console, "\n=== Total ===")
+	}
+
+	if err := state.recorder.close(); err != nil {
+		log.Fatalf("Error writing record file '%s': %v\n", cmp.Or(opts.record, opts.resume), err)
+	}
+	if opts.resume != "" && !state.interrupted() {
+		// Every host is done; a later -resume run starts a fresh scan
+		if err := os.Remove(opts.resume); err != nil {
+			log.Fatalf("Error removing checkpoint '%s': %v\n", opts.resume, err)
+		}
+	}
+	if err := state.sqlite.close(); err != nil {
+		log.Fatalf("Error writing database '%s': %v\n", opts.sqlite, err)
+	}
+
+	// Write structured results, then flush the output writer
+	if opts.live && opts.outputFormat == "text" {
+		for _, ip := range sortHosts(slices.Collect(maps.Keys(state.liveHosts))) {
+			state.saveToFile(state.liveHosts[ip])
+		}
+	}
+	if formatter := newResultFormatter(opts); formatter != nil {
+		if opts.sort {
+			sortResults(state.results)
+		}
+		state.writeMu.Lock() // The periodic flush may still be running
+		err := formatter.write(state.writer, state.results)
+		state.writeMu.Unlock()
+		if err != nil {
+			log.Fatalf("Error writing output file '%s': %v\n", opts.outputFile, err)
+		}
+	}
+	state.writeMu.Lock()
+	state.writer.Flush()
+	if state.offline != nil {
+		state.offline.Flush()
+	}
+	state.writeMu.Unlock()
+
+	// Write the reachability graph
+	if opts.dot != "" {
+		if err := writeDOT(opts.dot, vantagePoint(opts.scannerID, source), state.results); err != nil {
+			log.Fatalf("Error writing DOT file '%s': %v\n", opts.dot, err)
+		}
+	}
+
+	// Print the results
+	summary := state.formatSummary()
+	if state.deadlineReached() {
+		fmt.Fprint(console, "\nPing scan stopped at the deadline.\n"+summary)
+	} else if state.interrupted() {
+		fmt.Fprint(console, "\nPing scan interrupted.\n"+summary)
+	} else {
+		fmt.Fprint(console, "\nPing scan completed.\n"+summary)
+	}
+	if opts.baseline != "" {
+		reportBaseline(console, baseline, state.hostStates)
+	}
+	if known != nil {
+		if err := known.save(); err != nil {
+			log.Fatalf("Error writing known-hosts file '%s': %v\n", opts.knownHosts, err)
+		}
+	}
+
+	// Write the run manifest
+	if manifest != nil {
+		manifest.TotalHosts = state.totalHosts
+		manifest.Alive, manifest.Offline = state.aliveCount, state.notAliveCount
+		manifest.EndTime = time.Now().UTC()
+		if opts.manifest != "" {
+			if err := manifest.write(opts.manifest); err != nil {
+				log.Fatalf("Error writing manifest '%s': %v\n", opts.manifest, err)
+			}
+		}
+	}
+
+	// Bundle the complete scan record
+	if state.archive != nil {
+		if err := state.archive.close(summary, manifest); err != nil {
+			log.Fatalf("Error writing archive '%s': %v\n", opts.archive, err)
+		}
+	}
+
+	return state.summary()
+}
+
+// Probe every host of the target lines and wait for all of them to finish
+func (s *scanState) scanLines(lines []string) {
+	// Use a WaitGroup to wait for all goroutines to finish
+	var wg sync.WaitGroup
+
+	// IP:PORT lines need a TCP connect each, which the shared ICMP socket cannot send; check them alongside
+	if s.opts.collectWindow > 0 || s.opts.mux {
+		var icmpLines []string
+		for _, line := range lines {
+			if ip, _, ok := parseHostPort(line); !ok {
+				icmpLines = append(icmpLines, line)
+			} else if !s.scanHostPort(ip, line, &wg) {
+				break
+			}
+		}
+		lines = icmpLines
+	}
+
+	// Process each target line
+	if s.opts.collectWindow > 0 {
+		// Send every probe from one shared socket and collect replies together
+		s.sweep(lines)
+	} else if s.opts.mux {
+		// Send every probe from one loop and handle each host's result as soon as it is known
+		s.scanMux(lines)
+	} else if s.opts.randomize {
+		// Probe the expanded targets in random order to spread the load across the ranges
+		s.scanShuffled(lines)
+	} else {
+		for _, line := range lines {
+			if s.interrupted() {
+				break
+			}
+			// Check if the line is a valid IP, CIDR range, START-END range, or domain
+			if isBlock(line) {
+				// Handle CIDR range or START-END range
+				s.scanBlock(line, &wg)
+			} else if ip := net.ParseIP(line); ip != nil {
+				// Handle single IP, unless it is excluded or an earlier line already covered it
+				if s.skip(ip) {
+					continue
+				}
+				s.seen.add(line, s.opts.skipEdges)
+				if !s.acquire() {
+					break
+				}
+				wg.Add(1)
+				go func(ip string) {
+					defer wg.Done()
+					defer func() { <-s.sem }() // Release the semaphore slot
+					s.pingHost(ip, "")
+				}(line)
+			} else if ip, _, ok := parseHostPort(line); ok {
+				// Handle IP:PORT with a TCP connect to the port
+				if !s.scanHostPort(ip, line, &wg) {
+					break
+				}
+			} else if isDomain(line) {
+				// Handle domain
+				if !s.acquire() {
+					break
+				}
+				wg.Add(1)
+				go func(domain string) {
+					defer wg.Done()
+					defer func() { <-s.sem }() // Release the semaphore slot
+					s.pingDomain(domain)
+				}(line)
+			} else {
+				s.handleInvalid(line)
+			}
+		}
+	}
+
+	// Wait for all goroutines to complete
+	wg.Wait()
+	s.recountAmbiguous()
+}
+
+// Check the port of an IP:PORT target line in its own goroutine, unless the address is excluded;
+// returns false if the scan was interrupted first
+func (s *scanState) scanHostPort(ip, line string, wg *sync.WaitGroup) bool {
+	if s.isExcluded(net.ParseIP(ip)) {
+		return true
+	}
+	if !s.acquire() {
+		return false
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { <-s.sem }() // Release the semaphore slot
+		s.pingHost(ip, line)
+	}()
+	return true
+}
+
+// Resolve a domain target and probe its addresses
+func (s *scanState) pingDomain(domain string) {
+	ips, ok := s.resolve(domain)
+	if !ok {
+		s.handleUnresolved(domain)
+		return
+	}
+	// The addresses of a -resolve-all target are probed one after another in this slot, so -concurrency holds
+	for _, ip := range ips {
+		if s.interrupted() {
+			break
+		}
+		s.pingHost(ip, domain)
+	}
+}
+
+// Acquire a semaphore slot; returns false without one if the scan is interrupted first
+func (s *scanState) acquire() bool {
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// Check whether the scan was stopped early, by Ctrl-C or the -deadline
+func (s *scanState) interrupted() bool {
+	return s.ctx.Err() != nil
+}
+
+// Check whether the scan was stopped by the -deadline rather than by Ctrl-C
+func (s *scanState) deadlineReached() bool {
+	return errors.Is(s.ctx.Err(), context.DeadlineExceeded)
+}
+
+// Snapshot the scan counters
+func (s *scanState) summary() scanSummary {
+	return scanSummary{
+		alive:       atomic.LoadInt32(&s.aliveCount),
+		offline:     atomic.LoadInt32(&s.notAliveCount),
+		retried:     atomic.LoadInt32(&s.retriedCount),
+		interrupted: s.interrupted() && !s.deadlineReached(),
+		hosts:       s.hostStates,
+	}
+}
+
+// Round a scan duration for the summary: to the second, or to the millisecond for scans under a second
+func roundElapsed(elapsed time.Duration) time.Duration {
+	if elapsed < time.Second {
+		return elapsed.Round(time.Millisecond)
+	}
+	return elapsed.Round(time.Second)
+}
+
+// Format the end-of-scan summary
+func (s *scanState) formatSummary() string {
+	var b strings.Builder
+	if s.deadlineReached() {
+		b.WriteString("Deadline reached: results are partial\n")
+		fmt.Fprintf(&b, "Hosts skipped at the deadline: %d\n", s.totalHosts-s.progressCount)
+	} else if s.interrupted() {
+		b.WriteString("Scan interrupted: results are partial\n")
+	}
+	fmt.Fprintf(&b, "Alive hosts: %d\n", s.aliveCount)
+	fmt.Fprintf(&b, "Offline hosts: %d\n", s.notAliveCount)
+	if s.elapsed > 0 {
+		fmt.Fprintf(&b, "Scanned %d hosts in %s (%.0f hosts/s)\n", s.progressCount, roundElapsed(s.elapsed), float64(s.progressCount)/s.elapsed.Seconds())
+	}
+	if s.unresolvedCount > 0 {
+		fmt.Fprintf(&b, "Unresolvable domains: %d (counted as offline)\n", s.unresolvedCount)
+		for _, domain := range sortHosts(s.unresolved) {
+			fmt.Fprintf(&b, "  %s\n", domain)
+		}
+	}
+	if s.retriedCount > 0 {
+		fmt.Fprintf(&b, "%d hosts needed retries to respond\n", s.retriedCount)
+	}
+	if s.confirm != nil {
+		fmt.Fprintf(&b, "Unconfirmed hosts: %d\n", s.unconfirmedCount)
+	}
+	if s.sentCount > 0 {
+		fmt.Fprintf(&b, "Echo requests: %d sent, %d received, %.1f%% loss\n", s.sentCount, s.receivedCount, float64(s.sentCount-s.receivedCount)*100/float64(s.sentCount))
+	}
+	if s.excludedCount > 0 {
+		fmt.Fprintf(&b, "Excluded addresses skipped: %d\n", s.excludedCount)
+	}
+	if s.duplicates > 0 {
+		fmt.Fprintf(&b, "Duplicate addresses skipped: %d\n", s.duplicates)
+	}
+	if s.opts.firstOnly {
+		fmt.Fprintf(&b, "Hosts skipped after their block's first alive host: %d\n", s.firstOnlySkipped)
+	}
+	if s.opts.sample > 0 && s.opts.sample < s.population {
+		fmt.Fprintf(&b, "Sample: %d of %d distinct hosts in scope, drawn uniformly at random\n", s.opts.sample, s.population)
+	}
+	b.WriteString(s.tuner.report())
+	if reduced := s.pinger.Budget.ReducedCount(); reduced > 0 {
+		fmt.Fprintf(&b, "Hosts with reduced retries (deadline): %d\n", reduced)
+	}
+	if trips := s.pinger.Breaker.TripCount(); trips > 0 {
+		fmt.Fprintf(&b, "Circuit breaker trips: %d\n", trips)
+	}
+	if s.opts.recount {
+		fmt.Fprintf(&b, "Ambiguous hosts resolved: %d alive, %d dead\n", s.recountAlive, s.recountDead)
+	}
+	if s.opts.fragment {
+		fmt.Fprintf(&b, "Hosts failing fragmented probes: %d\n", s.fragFailed)
+	}
+	if s.known != nil {
+		fmt.Fprintf(&b, "New hosts: %d\n", s.known.added)
+	}
+	if s.opts.classify {
+		for _, tier := range netping.Tiers {
+			fmt.Fprintf(&b, "%s hosts: %d\n", strings.ToUpper(tier[:1])+tier[1:], s.tierCounts[tier])
+		}
+	}
+	return b.String()
+}
+
+// Save a line to the output file; probe goroutines share the writer, so writes are serialized
+func (s *scanState) saveToFile(line string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.writer.WriteString(line + "\n")
+}
+
+// Output file name that writes the results to standard output
+const stdoutPath = "-"
+
+// Flush the output file every second so streamed results reach disk during long scans
+func (s *scanState) flushPeriodically(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Second):
+		}
+		s.writeMu.Lock()
+		s.writer.Flush()
+		if s.offline != nil {
+			s.offline.Flush()
+		}
+		s.writeMu.Unlock()
+	}
+}
+
+// Append a line to the -offline-file, if one is set
+func (s *scanState) saveOffline(line string) {
+	if s.offline == nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.offline.WriteString(line + "\n")
+}
+
+// Record a host result for structured output
+func (s *scanState) record(result Result) {
+	if s.opts.outputFormat == "influx" && (s.known == nil || result.New) {
+		s.saveToFile(formatInfluxLine(result, s.source, time.Now()))
+	}
+	if s.opts.outputFormat == "jsonl" && !s.opts.sort && (s.known == nil || result.New) {
+		s.saveToFile(jsonLine(result))
+	}
+	// Streamed formats keep memory flat by holding results only for the reachability graph
+	streamed := (s.opts.outputFormat == "text" || s.opts.outputFormat == "jsonl") && !s.opts.sort
+	if (streamed && s.opts.dot == "") || (s.known != nil && !result.New) {
+		return
+	}
+	s.mu.Lock()
+	s.results = append(s.results, result)
+	s.mu.Unlock()
+}
+
+// Ping a host and handle results
+func (s *scanState) pingHost(ip, resolvedFrom string) {
+	s.probeHost(s.pingerFor(ip, resolvedFrom), ip, resolvedFrom)
+}
+
+// Ping a host with the given pinger and handle its result; returns false if the
+// pinger's context cut the probe short, leaving the host without a result
+func (s *scanState) probeHost(p *netping.Pinger, ip, resolvedFrom string) (Result, bool) {
+	result := Result{IP: ip, ResolvedFrom: resolvedFrom}
+	var reply netping.Reply
+	var alive bool
+	if _, port, ok := parseHostPort(resolvedFrom); ok {
+		// The target line names the port to check, so the host is alive if it accepts the connection
+		reply.Attempt, reply.RTT = checkHostPort(p, ip, port)
+		alive, result.Method = reply.Attempt > 0, methodTCP
+		if alive {
+			result.OpenPorts = []int{port}
+		}
+	} else if s.opts.classify {
+		result.Tier, reply = p.ClassifyHost(ip, s.opts.probes)
+		alive = reply.Attempt > 0
+		s.mu.Lock()
+		s.tierCounts[result.Tier]++
+		s.mu.Unlock()
+	} else if s.tcp != nil {
+		result.OpenPorts, alive, reply.RTT = s.tcp.scan(ip)
+		reply.Attempt = 1
+	} else if s.http != nil {
+		hostname := ""
+		if isDomain(resolvedFrom) {
+			hostname = resolvedFrom
+		}
+		reply.Attempt, result.HTTPStatus, result.Redirects, reply.RTT, alive = s.http.isHostAliveWithRetries(p, ip, hostname)
+	} else if arpReply, mac, onLink := s.probeARP(p, ip); onLink {
+		reply, alive, result.Method = arpReply, mac != nil, methodARP
+		if alive {
+			result.MAC, result.Vendor = mac.String(), netping.Vendor(mac)
+		}
+	} else {
+		if s.opts.pingCount > 0 {
+			var stats netping.Stats
+			stats, reply = p.PingStats(ip, s.opts.pingCount)
+			alive = reply.Attempt > 0
+			result.setStats(stats)
+			atomic.AddInt64(&s.sentCount, int64(stats.Sent))
+			atomic.AddInt64(&s.receivedCount, int64(stats.Received))
+		} else {
+			reply, alive = p.IsHostAliveWithRetries(ip)
+		}
+		if s.fallback != nil {
+			result.Method = methodICMP
+			if !alive && p.Context.Err() == nil {
+				// Hosts behind ICMP-dropping firewalls may still answer on a TCP port
+				result.OpenPorts, alive, reply.RTT = s.fallback.scan(ip)
+				reply.Attempt, result.Method = 1, methodTCP
+			}
+		}
+	}
+	// A probe cut short by Ctrl-C or -first-only says nothing about the host; leave it out of the results
+	if !alive && p.Context.Err() != nil {
+		return result, false
+	}
+	if alive {
+		result.Alive, result.Attempt, result.RTTMs = true, reply.Attempt, rttMs(reply.RTT)
+		result.TTL, result.OSGuess = reply.TTL, guessOS(reply.TTL)
+		s.confirmResult(&result)
+	}
+	s.lookupPTR(&result) // Runs in this host's semaphore slot
+	s.scanPorts(&result)
+	// Fragmentation and path MTU probes build IPv4 headers by hand, and need a host that answers ICMP
+	if result.Alive && result.Method != methodTCP && result.Method != methodARP && net.ParseIP(ip).To4() != nil {
+		if s.opts.fragment {
+			fragmentOK := p.IsFragmentedAliveWithRetries(ip)
+			result.FragmentOK = &fragmentOK
+			if !fragmentOK {
+				atomic.AddInt32(&s.fragFailed, 1)
+				if s.opts.verbose {
+					fmt.Printf("Host %s did not reply to fragmented probes\n", ip)
+				}
+			}
+		}
+		if s.opts.pmtu {
+			result.PathMTU = p.DiscoverPathMTU(ip, s.mtu, s.opts.pmtuProbes)
+			if s.opts.verbose {
+				fmt.Printf("Host %s path MTU %d\n", ip, result.PathMTU)
+			}
+		}
+	}
+	s.finishResult(result)
+	return result, true
+}
+
+// Resolve a domain target: every address with -resolve-all, otherwise the first one
+func (s *scanState) resolve(domain string) ([]string, bool) {
+	var ips []string
+	if s.opts.resolveAll {
+		ips = resolveDomainAll(domain, s.opts.ipv6)
+	} else if ip := resolveDomain(domain, s.opts.ipv6); ip != "" {
+		ips = []string{ip}
+	}
+	if len(ips) == 0 {
+		return nil, false
+	}
+	kept := s.filterResolved(ips)
+	s.trackDomain(domain, len(kept))
+	return kept, true
+}
+
+// Count a domain that could not be resolved as offline
+func (s *scanState) handleUnresolved(domain string) {
+	s.recorder.add(recordedEvent{Unresolved: domain})
+	atomic.AddInt32(&s.unresolvedCount, 1)
+	if s.opts.verbose {
+		s.mu.Lock()
+		s.unresolved = append(s.unresolved, domain)
+		s.mu.Unlock()
+		fmt.Println(s.colorize(colorYellow, fmt.Sprintf("Domain %s did not resolve", domain)))
+	}
+	metrics.observe(Result{ResolvedFrom: domain})
+	atomic.AddInt32(&s.notAliveCount, 1)
+	s.saveOffline(domain + " # did not resolve") // A comment, so the file can be scanned again as a target file
+	atomic.AddInt32(&s.progressCount, 1)
+	s.record(Result{ResolvedFrom: domain, Scanner: s.opts.scannerID})
+	if s.archive != nil {
+		s.archive.add(Result{ResolvedFrom: domain, Scanner: s.opts.scannerID})
+		s.archive.addError("Failed to resolve domain %s", domain)
+	}
+}
+
+// Format an alive host for -live: its text output line, or its result as a single-line JSON object
+func (s *scanState) liveLine(result Result) string {
+	if s.opts.liveFormat != "json" {
+		return textLine(result)
+	}
+	return jsonLine(result)
+}
+
+// Report a target line that is neither an IP, a CIDR range, nor a domain
+func (s *scanState) handleInvalid(line string) {
+	s.recorder.add(recordedEvent{Invalid: line})
+	message := "Invalid IP, CIDR range, or domain: " + line
+	if files, ok := s.invalidIn[line]; ok {
+		message = fmt.Sprintf("Invalid IP, CIDR range, or domain in %s: %s", files, line)
+	}
+	warnLog.Println(message)
+	if s.archive != nil {
+		s.archive.addError("%s", message)
+	}
+}
+
+// Count, print, and save the result of a probed host
+func (s *scanState) handleResult(result Result) {
+	s.tuner.observe(result)
+	// Replayed results keep the identity of the scanner that recorded them
+	if result.Scanner == "" {
+		result.Scanner = s.opts.scannerID
+	}
+	s.recorder.add(recordedEvent{Result: &result})
+	if !s.replaying {
+		s.sqlite.add(result) // Replayed and resumed results were logged by the scan that probed them
+	}
+	s.domainAddressDone(result.ResolvedFrom)
+	metrics.observe(result)
+	ip := result.IP
+	if s.hostStates != nil {
+		s.mu.Lock()
+		s.hostStates[ip] = result.Alive
+		s.mu.Unlock()
+	}
+	if result.Alive {
+		atomic.AddInt32(&s.aliveCount, 1)
+		if result.Attempt > 1 {
+			atomic.AddInt32(&s.retriedCount, 1)
+		}
+		if s.known != nil {
+			result.New = s.known.addIfNew(ip)
+		}
+		if s.opts.verbose {
+			fmt.Println(s.colorize(colorGreen, fmt.Sprintf("Host %s%s is alive on attempt %d%s%s%s%s%s%s%s%s", ip, ptrSuffix(result.PTR), result.Attempt, rttSuffix(result.RTTMs), statsSuffix(result), ttlSuffix(result.TTL, result.OSGuess), tierSuffix(result.Tier), methodSuffix(result.Method), macSuffix(result.MAC, result.Vendor), portsSuffix(result.OpenPorts), newSuffix(result.New))))
+		}
+		if s.opts.live && (s.known == nil || result.New) {
+			s.mu.Lock()
+			if _, printed := s.liveHosts[ip]; !printed {
+				fmt.Println(s.liveLine(result))
+				s.liveHosts[ip] = textLine(result)
+			}
+			s.mu.Unlock()
+		} else if s.opts.outputFormat == "text" && !s.opts.sort && (s.known == nil || result.New) {
+			s.saveToFile(textLine(result))
+		}
+		if s.perHost != nil && (s.known == nil || result.New) {
+			if err := s.perHost.write(result); err != nil {
+				errorLog.Printf("Error writing result file for %s: %v\n", ip, err)
+			}
+		}
+	} else {
+		atomic.AddInt32(&s.notAliveCount, 1)
+		if s.opts.verbose && result.Unconfirmed {
+			fmt.Println(s.colorize(colorRed, fmt.Sprintf("Host %s replied but was not confirmed", ip)))
+		} else if s.opts.verbose {
+			fmt.Println(s.colorize(colorRed, fmt.Sprintf("Host %s is not alive%s", ip, tierSuffix(result.Tier))))
+		}
+		s.saveOffline(ip)
+	}
+	s.record(result)
+	if s.archive != nil {
+		s.archive.add(result)
+	}
+	atomic.AddInt32(&s.progressCount, 1)
+}
+
+// Format a host's reverse-DNS name for verbose output
+func ptrSuffix(ptr string) string {
+	if ptr == "" {
+		return ""
+	}
+	return " (" + ptr + ")"
+}
+
+// Format a host's round-trip time for verbose output
+func rttSuffix(rttMs *float64) string {
+	if rttMs == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (rtt %.2fms)", *rttMs)
+}
+
+// Format a host's -ping-count statistics for verbose output
+func statsSuffix(result Result) string {
+	if result.Sent == 0 || result.Received == nil || result.RTTMinMs == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%d/%d received, %.0f%% loss, min/avg/max %.2f/%.2f/%.2fms)", *result.Received, result.Sent, *result.LossPct, *result.RTTMinMs, *result.RTTAvgMs, *result.RTTMaxMs)
+}
+
+// Format a reply's TTL and the OS it suggests for verbose output
+func ttlSuffix(ttl int, osGuess string) string {
+	if ttl == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (ttl %d, %s)", ttl, osGuess)
+}
+
+// Format a host's tier for verbose output
+func tierSuffix(tier string) string {
+	if tier == "" {
+		return ""
+	}
+	return " (" + tier + ")"
+}
+
+// Note hosts found by the -tcp-ports fallback or by ARP in verbose output
+func methodSuffix(method string) string {
+	if method != methodTCP && method != methodARP {
+		return ""
+	}
+	return " (via " + method + ")"
+}
+
+// Format the MAC address of a host found by ARP, and its vendor if known, for verbose output
+func macSuffix(mac, vendor string) string {
+	if mac == "" {
+		return ""
+	}
+	if vendor != "" {
+		return " (mac " + mac + ", " + vendor + ")"
+	}
+	return " (mac " + mac + ")"
+}
+
+// List a host's open ports for verbose output
+func portsSuffix(ports []int) string {
+	if len(ports) == 0 {
+		return ""
+	}
+	open := make([]string, len(ports))
+	for i, port := range ports {
+		open[i] = strconv.Itoa(port)
+	}
+	return " (open ports: " + strings.Join(open, ",") + ")"
+}
+
+// Flag newly seen hosts in verbose output
+func newSuffix(isNew bool) string {
+	if isNew {
+		return " [new]"
+	}
+	return ""
+}
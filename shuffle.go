@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Expand every target line, shuffle the hosts, and probe them in that order; the expanded list is held in memory
+func (s *scanState) scanShuffled(lines []string) {
+	targets := s.expand(lines)
+	shuffleTargets(s.rng, targets)
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		if !s.acquire() {
+			break
+		}
+		wg.Add(1)
+		go func(target sweepTarget) {
+			defer wg.Done()
+			defer func() { <-s.sem }() // Release the semaphore slot
+			if target.ip == "" {
+				s.pingDomain(target.resolvedFrom)
+			} else {
+				s.pingHost(target.ip, target.resolvedFrom)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+// Put the targets in a uniformly random order
+func shuffleTargets(rng *rand.Rand, targets []sweepTarget) {
+	rng.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+}
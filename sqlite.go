@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, so the binary still needs no C toolchain
+)
+
+// Tables of a -sqlite database; every scan adds a row to scans and one row per host to results
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	scan_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at TEXT NOT NULL,
+	scanner    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS results (
+	scan_id   INTEGER NOT NULL REFERENCES scans(scan_id),
+	timestamp TEXT NOT NULL,
+	ip        TEXT NOT NULL,
+	hostname  TEXT NOT NULL,
+	alive     INTEGER NOT NULL,
+	rtt_ms    REAL
+);
+CREATE INDEX IF NOT EXISTS results_ip ON results(ip, timestamp);
+`
+
+// Writes the results of one scan to a SQLite database as hosts finish, in a single transaction
+type sqliteStore struct {
+	mu     sync.Mutex
+	db     *sql.DB
+	tx     *sql.Tx
+	insert *sql.Stmt
+	scanID int64
+}
+
+// Open the database, creating its tables on first use, and register a new scan
+func openSQLiteStore(path, scanner string, startTime time.Time) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // SQLite allows one writer; queueing here avoids "database is locked"
+	store := &sqliteStore{db: db}
+	if err := store.begin(scanner, startTime); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Create the tables, register the scan, and start the transaction its results are written in
+func (s *sqliteStore) begin(scanner string, startTime time.Time) error {
+	if _, err := s.db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	var err error
+	if s.tx, err = s.db.Begin(); err != nil {
+		return err
+	}
+	scan, err := s.tx.Exec("INSERT INTO scans (started_at, scanner) VALUES (?, ?)", startTime.UTC().Format(time.RFC3339), scanner)
+	if err != nil {
+		s.tx.Rollback()
+		return err
+	}
+	if s.scanID, err = scan.LastInsertId(); err != nil {
+		s.tx.Rollback()
+		return err
+	}
+	s.insert, err = s.tx.Prepare("INSERT INTO results (scan_id, timestamp, ip, hostname, alive, rtt_ms) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		s.tx.Rollback()
+	}
+	return err
+}
+
+// Add the result of a finished host
+func (s *sqliteStore) add(result Result) {
+	if s == nil {
+		return
+	}
+	var rtt any
+	if result.RTTMs != nil {
+		rtt = *result.RTTMs
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.insert.Exec(s.scanID, time.Now().UTC().Format(time.RFC3339), result.IP, resultHostname(result), result.Alive, rtt); err != nil {
+		errorLog.Printf("Error writing the result of %s to the database: %v\n", result.IP, err)
+	}
+}
+
+// Commit the scan's results and close the database
+func (s *sqliteStore) close() error {
+	if s == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Read the non-empty target lines from a target file
+func loadTargets(path, format, filter string) ([]string, error) {
+	if format == "auto" {
+		detected, err := detectInputFormat(path)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	switch format {
+	case "json":
+		results, err := readJSONResults(path)
+		if err != nil {
+			return nil, err
+		}
+		return resultsToTargets(results, filter)
+	case "text":
+		if filter != "all" {
+			return nil, fmt.Errorf("-filter only applies to JSON target files")
+		}
+		return readTargetLines(path)
+	default:
+		return nil, fmt.Errorf("unknown input format '%s' (expected auto, text, or json)", format)
+	}
+}
+
+// Target line that is neither an IP, a CIDR range, a START-END range, nor a domain
+type invalidTarget struct {
+	file string
+	line int // Line number in the file
+	text string
+}
+
+// Find every invalid line of the target files before the scan starts, with its line number
+func findInvalidTargets(batches []targetBatch) []invalidTarget {
+	var invalid []invalidTarget
+	for _, batch := range batches {
+		// JSON target files only yield addresses and domains, so only text files are re-read for line numbers
+		if !slices.ContainsFunc(batch.lines, func(line string) bool { return !validTarget(line) }) {
+			continue
+		}
+		data, err := readTargetFile(batch.name)
+		if err != nil {
+			continue
+		}
+		scanner := newLineScanner(data)
+		for number := 1; scanner.Scan(); number++ {
+			for _, target := range lineTargets(scanner.Text()) {
+				if !validTarget(target) {
+					invalid = append(invalid, invalidTarget{batch.name, number, target})
+				}
+			}
+		}
+	}
+	return invalid
+}
+
+// Map each invalid target line to the quoted names of the files listing it, so errors point at the bad list
+func invalidLineSources(invalid []invalidTarget) map[string]string {
+	sources := map[string]string{}
+	for _, target := range invalid {
+		name := "'" + target.file + "'"
+		if files, ok := sources[target.text]; !ok {
+			sources[target.text] = name
+		} else if !strings.Contains(files, name) {
+			sources[target.text] = files + ", " + name
+		}
+	}
+	return sources
+}
+
+// Print every invalid target line found before the scan
+func reportInvalidTargets(logger *log.Logger, invalid []invalidTarget) {
+	logger.Printf("Found %d invalid target line(s):\n", len(invalid))
+	for _, target := range invalid {
+		logger.Printf("  '%s' line %d: %s\n", target.file, target.line, target.text)
+	}
+}
+
+// Check whether a target line is an IP, CIDR range, START-END range, or domain
+func validTarget(line string) bool {
+	if _, _, err := net.ParseCIDR(line); err == nil {
+		return true
+	}
+	if _, _, ok := parseIPRange(line); ok {
+		return true
+	}
+	return net.ParseIP(line) != nil || isDomain(line) || isHostPort(line)
+}
+
+// Split an IP:PORT target line, such as 10.0.0.5:443 or [2001:db8::5]:443, into its address and port
+func parseHostPort(line string) (string, int, bool) {
+	host, portText, err := net.SplitHostPort(line)
+	if err != nil {
+		return "", 0, false
+	}
+	ip := net.ParseIP(host)
+	port, err := strconv.Atoi(portText)
+	if ip == nil || err != nil || port < 1 || port > 65535 {
+		return "", 0, false
+	}
+	return ip.String(), port, true
+}
+
+// Check whether a target line is an IP:PORT entry, probed with a TCP connect to the port instead of ICMP
+func isHostPort(line string) bool {
+	_, _, ok := parseHostPort(line)
+	return ok
+}
+
+// Target file name that reads the targets from standard input
+const stdinPath = "-"
+
+// Standard input, read once so every pass (and every watch run) sees the same targets
+var (
+	stdinOnce sync.Once
+	stdinData []byte
+	stdinErr  error
+)
+
+// Read a whole target file, or standard input for "-"
+func readTargetFile(path string) ([]byte, error) {
+	if path != stdinPath {
+		return os.ReadFile(path)
+	}
+	stdinOnce.Do(func() {
+		stdinData, stdinErr = io.ReadAll(os.Stdin)
+	})
+	return stdinData, stdinErr
+}
+
+// Expand the -target-file arguments into the files to read: a directory stands for the regular files in it,
+// and a pattern such as targets/*.txt for the files it matches, both in name order. A file named twice is read once.
+func expandTargetFiles(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		var matches []string
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			entries, err := os.ReadDir(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") { // Skip hidden files like .gitkeep
+					matches = append(matches, filepath.Join(arg, entry.Name()))
+				}
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("directory '%s' contains no target files", arg)
+			}
+		} else if arg != stdinPath && strings.ContainsAny(arg, "*?[") {
+			globbed, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %v", arg, err)
+			}
+			for _, match := range globbed {
+				if info, err := os.Stat(match); err == nil && !info.IsDir() {
+					matches = append(matches, match)
+				}
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no target files match '%s'", arg)
+			}
+		} else {
+			matches = []string{arg} // Missing files are reported when they are read
+		}
+		for _, path := range matches {
+			if !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// Detect a previous JSON output by its leading '['
+func detectInputFormat(path string) (string, error) {
+	data, err := readTargetFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return "json", nil
+	}
+	return "text", nil
+}
+
+// Read a plain target file line by line, one target per line or several separated by commas or spaces
+func readTargetLines(path string) ([]string, error) {
+	data, err := readTargetFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	scanner := newLineScanner(data)
+	for scanner.Scan() {
+		lines = append(lines, lineTargets(scanner.Text())...)
+	}
+	return lines, scanner.Err()
+}
+
+// Scan the lines of a target file held in memory; the buffer may grow to the whole file, so
+// a generated file with every target on one enormous line is read instead of failing with bufio.ErrTooLong
+func newLineScanner(data []byte) *bufio.Scanner {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, max(len(data)+1, bufio.MaxScanTokenSize))
+	return scanner
+}
+
+// Spaces around the dash of a START - END range, removed so the range stays one target
+var rangeDashSpaces = regexp.MustCompile(`\s*-\s*`)
+
+// Split a target file line into its targets, separated by commas or whitespace, after stripping a '#' comment;
+// comment-only lines have none
+func lineTargets(text string) []string {
+	targets, _ := splitTargetLine(text)
+	return targets
+}
+
+// Split a target file line into its targets and its key=value annotations such as timeout=5s
+func splitTargetLine(text string) (targets, annotations []string) {
+	line, _, _ := strings.Cut(text, "#")
+	line = rangeDashSpaces.ReplaceAllString(strings.TrimSpace(line), "-")
+	for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if strings.Contains(field, "=") {
+			annotations = append(annotations, field)
+		} else {
+			targets = append(targets, field)
+		}
+	}
+	return targets, annotations
+}
+
+// Increment an IP address
+func incrementIP(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] > 0 {
+			break
+		}
+	}
+}
+
+// Check if a string is a domain; an all-numeric top-level label marks a mistyped IP such as 10.0.0.300
+func isDomain(host string) bool {
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") || strings.ContainsAny(host, "/:") || looksLikeRange(host) {
+		return false
+	}
+	tld := host[strings.LastIndex(strings.TrimSuffix(host, "."), ".")+1:]
+	return strings.Trim(tld, "0123456789.") != ""
+}
+
+// Resolve a domain to its IP address
+func resolveDomain(domain string, withIPv6 bool) string {
+	if ips := resolveDomainAll(domain, withIPv6); len(ips) > 0 {
+		return ips[0] // Return the first address, preferring IPv4
+	}
+	return ""
+}
+
+// Resolve a domain to every IPv4 address it has, followed by its IPv6 addresses if requested
+func resolveDomainAll(domain string, withIPv6 bool) []string {
+	ips, err := net.LookupIP(domain)
+	if err != nil {
+		warnLog.Printf("Failed to resolve domain %s: %v\n", domain, err)
+		return nil
+	}
+	var addresses, ipv6Addresses []string
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			addresses = append(addresses, ip.String())
+		} else if withIPv6 {
+			ipv6Addresses = append(ipv6Addresses, ip.String())
+		}
+	}
+	return append(addresses, ipv6Addresses...)
+}
+
+// Count the hosts described by a target line (IP, CIDR range, or domain)
+func countHosts(line string, skipEdges bool) (int32, bool) {
+	if _, _, ok := lineBounds(line, skipEdges); ok {
+		var count int32
+		for range lineHosts(line, skipEdges) {
+			count++
+		}
+		return count, true
+	}
+	if isDomain(line) || isHostPort(line) {
+		return 1, true
+	}
+	return 0, false
+}
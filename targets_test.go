@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitTargetLine(t *testing.T) {
+	tests := []struct {
+		text        string
+		targets     []string
+		annotations []string
+	}{
+		{"10.0.0.1", []string{"10.0.0.1"}, nil},
+		{"  10.0.0.1  # gateway", []string{"10.0.0.1"}, nil},
+		{"# comment only", nil, nil},
+		{"", nil, nil},
+		{"10.0.0.1, 10.0.0.2 10.0.0.3", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, nil},
+		{"10.0.0.1 - 10.0.0.9", []string{"10.0.0.1-10.0.0.9"}, nil},
+		{"10.0.0.1 -20, example.com", []string{"10.0.0.1-20", "example.com"}, nil},
+		{"10.0.0.0/24 timeout=5s", []string{"10.0.0.0/24"}, []string{"timeout=5s"}},
+		{"example.com timeout=2s # slow link", []string{"example.com"}, []string{"timeout=2s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			targets, annotations := splitTargetLine(tt.text)
+			if !slices.Equal(targets, tt.targets) {
+				t.Errorf("targets = %q, want %q", targets, tt.targets)
+			}
+			if !slices.Equal(annotations, tt.annotations) {
+				t.Errorf("annotations = %q, want %q", annotations, tt.annotations)
+			}
+		})
+	}
+}
+
+func TestIsDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"example.com.", true},
+		{"sub.example.co.uk", true},
+		{"host-1.example.net", true},
+		{"localhost", false},
+		{"10.0.0.1", false},
+		{"10.0.0.300", false},
+		{"10.0.0.1.", false},
+		{"2001:db8::1", false},
+		{"10.0.0.0/24", false},
+		{"10.0.0.1-20", false},
+		{"example.com:443", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := isDomain(tt.host); got != tt.want {
+				t.Errorf("isDomain(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHostPort(t *testing.T) {
+	tests := []struct {
+		line string
+		ip   string // Empty when the line is not IP:PORT
+		port int
+	}{
+		{"10.0.0.5:443", "10.0.0.5", 443},
+		{"[2001:db8::5]:22", "2001:db8::5", 22},
+		{"[2001:0db8:0::5]:22", "2001:db8::5", 22},
+		{"10.0.0.5:1", "10.0.0.5", 1},
+		{"10.0.0.5:65535", "10.0.0.5", 65535},
+		{"10.0.0.5:0", "", 0},
+		{"10.0.0.5:65536", "", 0},
+		{"10.0.0.5:http", "", 0},
+		{"10.0.0.5", "", 0},
+		{"2001:db8::5", "", 0},
+		{"example.com:443", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			ip, port, ok := parseHostPort(tt.line)
+			if ok != (tt.ip != "") {
+				t.Fatalf("ok = %v, want %v", ok, tt.ip != "")
+			}
+			if ip != tt.ip || port != tt.port {
+				t.Errorf("parsed = %s port %d, want %s port %d", ip, port, tt.ip, tt.port)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"pinger/netping"
+)
+
+// Default ports checked by -probe tcp when -tcp is not given
+const defaultTCPPorts = "80,443"
+
+// Checks a list of TCP ports per host; a host is alive if any port is open or refuses the connection
+type tcpProber struct {
+	ports       []int
+	timeout     time.Duration
+	concurrency int // Ports checked at once per host
+}
+
+// Parse a comma-separated port list such as "22,80,443"
+func parsePorts(spec string) ([]int, error) {
+	var ports []int
+	for _, field := range strings.Split(spec, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port '%s'", field)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// Connect to a single port, reporting whether it is open and whether the host answered at all
+func dialPort(ip string, port int, timeout time.Duration) (open bool, answered bool) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), timeout)
+	if err == nil {
+		conn.Close()
+		return true, true
+	}
+	return false, errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Connect to a single port; a variable so tests can stand in for the network
+var dialTCP = dialPort
+
+// Check the port of an IP:PORT target line with the host's pinger settings, so timeout= annotations apply:
+// up to p.Retries connects of p.Timeout each, returning the 1-based attempt that connected (0 if none did) and its connect time
+func checkHostPort(p *netping.Pinger, ip string, port int) (int, time.Duration) {
+	for i := 0; i < p.Retries && p.Context.Err() == nil; i++ {
+		start := time.Now()
+		if open, _ := dialTCP(ip, port, p.Timeout); open {
+			return i + 1, time.Since(start)
+		}
+	}
+	return 0, 0
+}
+
+// Check every port of the host, returning the sorted open ports, whether the host answered, and the fastest answer
+func (t *tcpProber) scan(ip string) ([]int, bool, time.Duration) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var open []int
+	var answered bool
+	var fastest time.Duration
+
+	sem := make(chan struct{}, t.concurrency)
+	for _, port := range t.ports {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			isOpen, isAnswered := dialPort(ip, port, t.timeout)
+			rtt := time.Since(start)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if isOpen {
+				open = append(open, port)
+			}
+			if isAnswered {
+				if !answered || rtt < fastest {
+					fastest = rtt
+				}
+				answered = true
+			}
+		}(port)
+	}
+	wg.Wait()
+
+	sort.Ints(open)
+	return open, answered, fastest
+}
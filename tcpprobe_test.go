@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"pinger/netping"
+)
+
+func TestCheckHostPortTimeoutOverride(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		openOn      int // Attempt that connects (0 = none)
+		wantAttempt int
+		wantTimeout time.Duration
+		wantDials   int
+	}{
+		{"default timeout", "10.0.0.5:443", 1, 1, time.Second, 1},
+		{"timeout= override", "10.0.0.6:443", 1, 1, 5 * time.Second, 1},
+		{"retried until open", "10.0.0.5:443", 2, 2, time.Second, 2},
+		{"every retry used", "10.0.0.6:443", 0, 0, 5 * time.Second, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := netping.NewPinger(netping.NewLimiter(0, 0), "", 0, "")
+			pinger.Timeout, pinger.Retries = time.Second, 3
+			s := &scanState{pinger: pinger, timeouts: map[string]time.Duration{"10.0.0.6:443": 5 * time.Second}}
+
+			var timeouts []time.Duration
+			dialTCP = func(ip string, port int, timeout time.Duration) (bool, bool) {
+				timeouts = append(timeouts, timeout)
+				return len(timeouts) == tt.openOn, true
+			}
+			defer func() { dialTCP = dialPort }()
+
+			ip, port, _ := parseHostPort(tt.line)
+			attempt, _ := checkHostPort(s.pingerFor(ip, tt.line), ip, port)
+			if attempt != tt.wantAttempt {
+				t.Errorf("attempt = %d, want %d", attempt, tt.wantAttempt)
+			}
+			if len(timeouts) != tt.wantDials {
+				t.Fatalf("dialed %d times, want %d", len(timeouts), tt.wantDials)
+			}
+			for _, timeout := range timeouts {
+				if timeout != tt.wantTimeout {
+					t.Errorf("dial timeout = %v, want %v", timeout, tt.wantTimeout)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pinger/netping"
+)
+
+// Annotation of a target file line that overrides -timeout for its targets, e.g. "10.0.0.1 timeout=5s"
+const timeoutAnnotation = "timeout"
+
+// Read the timeout= annotations of the text target files, mapping every target of an annotated line to its timeout
+func loadTimeoutOverrides(paths []string, format string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, path := range paths {
+		fileFormat := format
+		if format == "auto" {
+			detected, err := detectInputFormat(path)
+			if err != nil {
+				return nil, err
+			}
+			fileFormat = detected
+		}
+		if fileFormat != "text" {
+			continue // JSON target files have no annotations
+		}
+		data, err := readTargetFile(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := newLineScanner(data)
+		for number := 1; scanner.Scan(); number++ {
+			targets, annotations := splitTargetLine(scanner.Text())
+			for _, annotation := range annotations {
+				key, value, _ := strings.Cut(annotation, "=")
+				if key != timeoutAnnotation {
+					return nil, fmt.Errorf("'%s' line %d: unknown annotation '%s' (expected timeout=DURATION)", path, number, annotation)
+				}
+				timeout, err := time.ParseDuration(value)
+				if err != nil || timeout <= 0 {
+					return nil, fmt.Errorf("'%s' line %d: invalid timeout '%s'", path, number, value)
+				}
+				for _, target := range targets {
+					timeouts[target] = timeout
+				}
+			}
+		}
+	}
+	return timeouts, nil
+}
+
+// Pinger for a host: the scan's pinger, or a copy with the timeout= override of the target line it came from
+func (s *scanState) pingerFor(ip, resolvedFrom string) *netping.Pinger {
+	line := resolvedFrom
+	if line == "" {
+		line = ip // A single address is its own target line
+	}
+	timeout, ok := s.timeouts[line]
+	if !ok {
+		return s.pinger
+	}
+	pinger := *s.pinger
+	pinger.Timeout = timeout
+	return &pinger
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	webhookAttempts = 4                // Tries per transition before it is dropped
+	webhookTimeout  = 10 * time.Second // Limit of each POST
+	webhookBackoff  = time.Second      // Wait before the second try, doubled before each later one
+)
+
+// Status change of a host between two scans of a watch, as posted to -webhook-url
+type hostTransition struct {
+	IP        string    `json:"ip"`
+	OldStatus string    `json:"old_status"` // alive or offline
+	NewStatus string    `json:"new_status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Posts every host transition of a watch to a URL in order, from a background goroutine so slow
+// endpoints do not delay the next scan
+type webhook struct {
+	url    string
+	client *http.Client
+	queue  chan hostTransition
+	done   chan struct{}
+}
+
+// Start posting to the URL; nil if no URL is set
+func newWebhook(target string) (*webhook, error) {
+	if target == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid -webhook-url '%s' (expected an http or https URL)", target)
+	}
+	w := &webhook{
+		url:    target,
+		client: &http.Client{Timeout: webhookTimeout},
+		queue:  make(chan hostTransition, 1024),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Queue the transitions between two scans; when the queue is full, a transition is dropped with a warning
+// rather than holding up the next scan
+func (w *webhook) notify(previous, current map[string]bool, at time.Time) {
+	if w == nil {
+		return
+	}
+	for _, ip := range sortHosts(slices.Collect(maps.Keys(current))) {
+		if wasAlive, seen := previous[ip]; seen && wasAlive != current[ip] {
+			select {
+			case w.queue <- hostTransition{ip, hostStatus(wasAlive), hostStatus(current[ip]), at.UTC()}:
+			default:
+				warnLog.Printf("Webhook queue full: dropped the transition of %s\n", ip)
+			}
+		}
+	}
+}
+
+// Post the queued transitions until the webhook is closed
+func (w *webhook) run() {
+	defer close(w.done)
+	for transition := range w.queue {
+		if err := w.post(transition); err != nil {
+			warnLog.Printf("Failed to post the transition of %s to the webhook: %v\n", transition.IP, err)
+		}
+	}
+}
+
+// Post one transition, retrying network errors and 429/5xx responses with a doubling wait
+func (w *webhook) post(transition hostTransition) error {
+	body, err := json.Marshal(transition)
+	if err != nil {
+		return err
+	}
+	delay := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err = w.send(body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		if _, permanent := err.(webhookRejected); permanent {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Response status the webhook will not accept on a retry either
+type webhookRejected struct {
+	status string
+}
+
+func (e webhookRejected) Error() string {
+	return "rejected with " + e.status
+}
+
+// Send one POST; responses other than 2xx are errors
+func (w *webhook) send(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return webhookRejected{resp.Status}
+}
+
+// Wait until every queued transition has been posted or dropped
+func (w *webhook) close() {
+	if w == nil {
+		return
+	}
+	close(w.queue)
+	<-w.done
+}
+
+// Status name of a host in webhook payloads
+func hostStatus(alive bool) string {
+	if alive {
+		return "alive"
+	}
+	return "offline"
+}